@@ -30,6 +30,10 @@ type Config struct {
 	App                    *echo.Echo
 	Logger                 *slog.Logger
 	LoggingMiddlwareConfig LoggingMiddlwareConfig
+	// Names of headers that are never logged by LoggingMiddleware or DebugMiddleware.
+	// Used if LoggingMiddlwareConfig.SensitiveHeaders is not set.
+	// If nil, use DefaultSensitiveHeaders.
+	SensitiveHeaders []string
 	// Origins for echo's CORS middleware.
 	// If it and CorsConfig are empty, do not add the middleware.
 	CorsOrigins []string
@@ -37,6 +41,13 @@ type Config struct {
 	// Supercedes CorsOrigins.
 	// If it and CorsOrigins are empty, do not add the middleware.
 	CorsConfig *middleware.CORSConfig
+	// How long, in seconds, browsers can cache the result of a CORS preflight request.
+	// Used if CorsConfig.MaxAge is not set. Defaults to 0 (browsers use their own default).
+	CorsMaxAge int
+	// Request headers allowed in CORS requests.
+	// Used if CorsConfig.AllowHeaders is not set. Defaults to echo's default
+	// (the headers requested in the preflight request are allowed).
+	CorsAllowHeaders []string
 	// Return this from the health endpoint.
 	// Defaults to {"o":"k"}.
 	HealthResponse map[string]interface{}
@@ -92,13 +103,34 @@ func New(cfg Config) *echo.Echo {
 	e.Logger.SetOutput(os.Stdout)
 	e.HideBanner = true
 	e.HTTPErrorHandler = NewHTTPErrorHandler(e)
-	e.Use(LoggingMiddlewareWithConfig(cfg.Logger, cfg.LoggingMiddlwareConfig))
+	if cfg.LoggingMiddlwareConfig.SensitiveHeaders == nil {
+		cfg.LoggingMiddlwareConfig.SensitiveHeaders = cfg.SensitiveHeaders
+	}
 	if cfg.CorsConfig == nil && cfg.CorsOrigins != nil {
 		cfg.CorsConfig = &middleware.CORSConfig{AllowOrigins: cfg.CorsOrigins, AllowCredentials: true}
 	}
+	// Middleware order matters:
+	//   - Logging is outermost, so it logs every request (including CORS preflight requests,
+	//     which the CORS middleware responds to without calling the handler),
+	//     and recovers panics and renders errors for everything inside it.
+	//   - CORS comes next, and writes its headers before calling the handler.
+	//     Preflight requests are tagged in the request logs (cors_preflight=true) first.
+	//     Since errors are rendered (by Logging) onto the same response after the handler returns,
+	//     CORS headers are present on api.Error responses, panics, and 404s.
+	middlewares := []echo.MiddlewareFunc{
+		LoggingMiddlewareWithConfig(cfg.Logger, cfg.LoggingMiddlwareConfig),
+	}
 	if cfg.CorsConfig != nil {
-		e.Use(middleware.CORSWithConfig(*cfg.CorsConfig))
+		corsCfg := *cfg.CorsConfig
+		if corsCfg.MaxAge == 0 {
+			corsCfg.MaxAge = cfg.CorsMaxAge
+		}
+		if corsCfg.AllowHeaders == nil {
+			corsCfg.AllowHeaders = cfg.CorsAllowHeaders
+		}
+		middlewares = append(middlewares, corsPreflightLoggingMiddleware, middleware.CORSWithConfig(corsCfg))
 	}
+	e.Use(middlewares...)
 	e.GET(cfg.HealthPath, cfg.HealthHandler)
 	e.GET(cfg.StatusPath, cfg.StatusHandler)
 	return e
@@ -1,7 +1,9 @@
 package api_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/lithictech/go-aperitif/v2/api"
 	"github.com/lithictech/go-aperitif/v2/api/apiparams"
@@ -14,7 +16,9 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAPI(t *testing.T) {
@@ -29,12 +33,11 @@ var _ = Describe("API", func() {
 	var logHook *logctx.Hook
 
 	BeforeEach(func() {
-		logger, logHook = logctx.NewNullLogger()
-		e = api.New(api.Config{
-			Logger:         logger,
+		app := NewApp(api.Config{
 			HealthResponse: map[string]interface{}{"o": "k"},
 			StatusResponse: map[string]interface{}{"it": "me"},
 		})
+		e, logger, logHook = app.Echo, app.Logger, app.Hook
 	})
 
 	It("has a health endpoint", func() {
@@ -87,6 +90,100 @@ var _ = Describe("API", func() {
 		Expect(e2).To(BeIdenticalTo(e1))
 	})
 
+	Describe("NewHealthHandler", func() {
+		It("reports the result and latency of each check", func() {
+			e = api.New(api.Config{
+				Logger: logger,
+				HealthHandler: api.NewHealthHandler(map[string]api.HealthCheck{
+					"db": func(context.Context) error { return nil },
+				}),
+			})
+			rr := Serve(e, GetRequest("/healthz"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(rr).To(HaveJsonBody(HaveKeyWithValue("db", And(
+				HaveKeyWithValue("ok", true),
+				HaveKeyWithValue("latency_ms", BeNumerically(">=", 0)),
+				Not(HaveKey("error")),
+			))))
+		})
+		It("responds with a 503 if any check fails", func() {
+			e = api.New(api.Config{
+				Logger: logger,
+				HealthHandler: api.NewHealthHandler(map[string]api.HealthCheck{
+					"db":    func(context.Context) error { return nil },
+					"redis": func(context.Context) error { return errors.New("no redis") },
+				}),
+			})
+			rr := Serve(e, GetRequest("/healthz"))
+			Expect(rr).To(HaveResponseCode(503))
+			Expect(rr).To(HaveJsonBody(And(
+				HaveKeyWithValue("db", HaveKeyWithValue("ok", true)),
+				HaveKeyWithValue("redis", And(
+					HaveKeyWithValue("ok", false),
+					HaveKeyWithValue("error", "no redis"),
+				)),
+			)))
+		})
+		It("fails a check that panics", func() {
+			e = api.New(api.Config{
+				Logger: logger,
+				HealthHandler: api.NewHealthHandler(map[string]api.HealthCheck{
+					"db":    func(context.Context) error { return nil },
+					"redis": func(context.Context) error { panic("no redis") },
+				}),
+			})
+			rr := Serve(e, GetRequest("/healthz"))
+			Expect(rr).To(HaveResponseCode(503))
+			Expect(rr).To(HaveJsonBody(And(
+				HaveKeyWithValue("db", HaveKeyWithValue("ok", true)),
+				HaveKeyWithValue("redis", And(
+					HaveKeyWithValue("ok", false),
+					HaveKeyWithValue("error", "panic: no redis"),
+				)),
+			)))
+		})
+		It("fails a check that does not finish before the timeout, without blocking other checks", func() {
+			hang := make(chan struct{})
+			DeferCleanup(func() { close(hang) })
+			e = api.New(api.Config{
+				Logger: logger,
+				HealthHandler: api.NewHealthHandlerWithConfig(api.HealthConfig{
+					Checks: map[string]api.HealthCheck{
+						"db": func(context.Context) error { return nil },
+						"redis": func(ctx context.Context) error {
+							<-ctx.Done()
+							return ctx.Err()
+						},
+						"ignores_ctx": func(context.Context) error {
+							<-hang
+							return nil
+						},
+					},
+					Timeout: 20 * time.Millisecond,
+				}),
+			})
+			start := time.Now()
+			rr := Serve(e, GetRequest("/healthz"))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Expect(rr).To(HaveResponseCode(503))
+			Expect(rr).To(HaveJsonBody(And(
+				HaveKeyWithValue("db", And(
+					HaveKeyWithValue("ok", true),
+					HaveKeyWithValue("latency_ms", BeNumerically("<", 20)),
+				)),
+				HaveKeyWithValue("redis", And(
+					HaveKeyWithValue("ok", false),
+					HaveKeyWithValue("error", "context deadline exceeded"),
+				)),
+				HaveKeyWithValue("ignores_ctx", And(
+					HaveKeyWithValue("ok", false),
+					HaveKeyWithValue("error", "context deadline exceeded"),
+					HaveKeyWithValue("latency_ms", BeNumerically(">=", 20)),
+				)),
+			)))
+		})
+	})
+
 	Describe("tracing", func() {
 		It("uses the trace id in the Trace-Id header", func() {
 			req := GetRequest("/healthz")
@@ -150,6 +247,19 @@ var _ = Describe("API", func() {
 				HaveKeyWithValue("request_latency_ms", BeNumerically(">=", 0)),
 			))
 		})
+		It("keeps fields added with AddLogFields for the rest of the request", func() {
+			e.GET("/", func(c echo.Context) error {
+				added := api.AddLogFields(c, "user_id", 5)
+				Expect(added).To(BeIdenticalTo(api.Logger(c)))
+				api.Logger(c).Info("fromendpoint")
+				return c.String(200, "ok")
+			})
+			Expect(Serve(e, GetRequest("/"))).To(HaveResponseCode(200))
+			Expect(logHook.Records()).To(HaveLen(2))
+			Expect(logHook.Records()[0].AttrMap()).To(HaveKeyWithValue("user_id", BeEquivalentTo(5)))
+			Expect(logHook.Records()[1].Record.Message).To(Equal("request_finished"))
+			Expect(logHook.Records()[1].AttrMap()).To(HaveKeyWithValue("user_id", BeEquivalentTo(5)))
+		})
 		It("logs 500+ at error", func() {
 			e.GET("/", func(c echo.Context) error {
 				return c.String(500, "oh")
@@ -158,6 +268,98 @@ var _ = Describe("API", func() {
 			Expect(logHook.Records()).To(HaveLen(1))
 			Expect(logHook.Records()[0].Record.Level).To(Equal(slog.LevelError))
 		})
+		It("can coalesce repeated 500+ logs", func() {
+			e = api.New(api.Config{
+				Logger:                 logger,
+				LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{ErrorLogWindow: 50 * time.Millisecond},
+			})
+			e.GET("/fail/:id", func(c echo.Context) error {
+				return c.String(500, "oh")
+			})
+			e.GET("/other", func(c echo.Context) error {
+				return c.String(503, "oh")
+			})
+			for i := 0; i < 5; i++ {
+				Expect(Serve(e, GetRequest(fmt.Sprintf("/fail/%d", i)))).To(HaveResponseCode(500))
+			}
+			Expect(Serve(e, GetRequest("/other"))).To(HaveResponseCode(503))
+			Expect(logHook.Records()).To(HaveLen(2))
+			Expect(logHook.Records()[0].Record.Message).To(Equal("request_finished"))
+			Expect(logHook.Records()[1].AttrMap()).To(HaveKeyWithValue("request_path", "/other"))
+			Eventually(logHook.Records).Should(HaveLen(3))
+			Expect(logHook.LastRecord().Record.Message).To(Equal("request_errors_coalesced"))
+			Expect(logHook.LastRecord().Record.Level).To(Equal(slog.LevelError))
+			Expect(logHook.LastRecord().AttrMap()).To(And(
+				HaveKeyWithValue("request_method", "GET"),
+				HaveKeyWithValue("request_path", "/fail/:id"),
+				HaveKeyWithValue("request_status", int64(500)),
+				HaveKeyWithValue("request_count", int64(4)),
+			))
+
+			// The window has reset, so the next error is logged as usual.
+			Expect(Serve(e, GetRequest("/fail/1"))).To(HaveResponseCode(500))
+			Expect(logHook.LastRecord().Record.Message).To(Equal("request_finished"))
+		})
+		It("truncates long queries", func() {
+			app := NewApp(api.Config{LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{MaxQueryLogLength: 10}})
+			app.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			_, records := app.ServeAndCapture(GetRequest("/?q=" + strings.Repeat("x", 100)))
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].AttrMap()).To(HaveKeyWithValue("request_query", "q=xxxxxxx…"))
+
+			_, records = app.ServeAndCapture(GetRequest("/?q=x"))
+			Expect(records[0].AttrMap()).To(HaveKeyWithValue("request_query", "q=x"))
+		})
+		It("does not split multibyte characters when truncating", func() {
+			app := NewApp(api.Config{LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{MaxQueryLogLength: 5}})
+			app.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			req := GetRequest("/")
+			req.URL.RawQuery = "q=日本語テキスト"
+			req.RequestURI = "/?" + req.URL.RawQuery
+			_, records := app.ServeAndCapture(req)
+			Expect(records[0].AttrMap()).To(And(
+				HaveKeyWithValue("request_query", "q=日本…"),
+				HaveKeyWithValue("request_uri", "/?q=…"),
+			))
+		})
+		It("does not truncate if MaxQueryLogLength is negative", func() {
+			app := NewApp(api.Config{LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{MaxQueryLogLength: -1}})
+			app.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			q := "q=" + strings.Repeat("x", 3000)
+			_, records := app.ServeAndCapture(GetRequest("/?" + q))
+			Expect(records[0].AttrMap()).To(HaveKeyWithValue("request_query", q))
+		})
+		It("logs the duration of request spans", func() {
+			app := NewApp(api.Config{})
+			app.GET("/", func(c echo.Context) error {
+				stop := api.TimeSpan(c, "db")
+				time.Sleep(2 * time.Millisecond)
+				stop()
+				api.TimeSpan(c, "render")()
+				api.TimeSpan(c, "db")()
+				return c.String(200, "ok")
+			})
+			rr, records := app.ServeAndCapture(GetRequest("/"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].AttrMap()).To(HaveKeyWithValue("request_spans", HaveExactElements(
+				And(HaveField("Key", "db"), HaveField("Value.Int64()", BeNumerically(">=", 2))),
+				HaveField("Key", "render"),
+			)))
+		})
+		It("does not log request spans if there are none", func() {
+			e.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			Expect(Serve(e, GetRequest("/"))).To(HaveResponseCode(200))
+			Expect(logHook.Records()[0].AttrMap()).ToNot(HaveKey("request_spans"))
+		})
 		It("logs 400 to 499 as warn", func() {
 			e.GET("/", func(c echo.Context) error {
 				return c.String(400, "client err")
@@ -193,8 +395,52 @@ var _ = Describe("API", func() {
 			Expect(Serve(e, GetRequest("/", SetReqHeader("ReqHead", "ReqHeadVal")))).To(HaveResponseCode(200))
 			Expect(logHook.Records()).To(HaveLen(1))
 			Expect(logHook.Records()[0].AttrMap()).To(And(
-				HaveKeyWithValue("request_header.Reqhead", "ReqHeadVal"),
-				HaveKeyWithValue("response_header.Reshead", "ResHeadVal"),
+				HaveKeyWithValue("request_header", ContainElement(slog.String("Reqhead", "ReqHeadVal"))),
+				HaveKeyWithValue("response_header", ContainElement(slog.String("Reshead", "ResHeadVal"))),
+			))
+		})
+		It("does not log sensitive headers", func() {
+			e = api.New(api.Config{
+				Logger: logger,
+				LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{
+					RequestHeaders:  true,
+					ResponseHeaders: true,
+				},
+			})
+			e.GET("/", func(c echo.Context) error {
+				c.Response().Header().Set("Set-Cookie", "x=1")
+				return c.String(200, "ok")
+			})
+			req := GetRequest("/", SetReqHeader("Authorization", "Bearer x"), SetReqHeader("Cookie", "y=2"), SetReqHeader("ReqHead", "v"))
+			Expect(Serve(e, req)).To(HaveResponseCode(200))
+			Expect(logHook.Records()).To(HaveLen(1))
+			Expect(logHook.Records()[0].AttrMap()).To(And(
+				HaveKeyWithValue("request_header", And(
+					ContainElement(HaveField("Key", "Reqhead")),
+					Not(ContainElement(HaveField("Key", "Authorization"))),
+					Not(ContainElement(HaveField("Key", "Cookie"))),
+				)),
+				HaveKeyWithValue("response_header", Not(ContainElement(HaveField("Key", "Set-Cookie")))),
+			))
+		})
+		It("can configure the sensitive headers", func() {
+			e = api.New(api.Config{
+				Logger:           logger,
+				SensitiveHeaders: []string{"x-api-key"},
+				LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{
+					RequestHeaders: true,
+				},
+			})
+			e.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			req := GetRequest("/", SetReqHeader("X-Api-Key", "secret"), SetReqHeader("Cookie", "y=2"))
+			Expect(Serve(e, req)).To(HaveResponseCode(200))
+			Expect(logHook.Records()[0].AttrMap()).To(And(
+				HaveKeyWithValue("request_header", And(
+					ContainElement(HaveField("Key", "Cookie")),
+					Not(ContainElement(HaveField("Key", "X-Api-Key"))),
+				)),
 			))
 		})
 		It("can use custom DoLog, BeforeRequest, and AfterRequest hooks", func() {
@@ -224,6 +470,32 @@ var _ = Describe("API", func() {
 				HaveKeyWithValue("after", BeEquivalentTo(2)),
 			))
 		})
+		It("calls multiple BeforeRequest and AfterRequest hooks in order", func() {
+			addField := func(k string, v int) func(echo.Context, *slog.Logger) *slog.Logger {
+				return func(_ echo.Context, e *slog.Logger) *slog.Logger {
+					return e.With(k, v)
+				}
+			}
+			e = api.New(api.Config{
+				Logger: logger,
+				LoggingMiddlwareConfig: api.LoggingMiddlwareConfig{
+					BeforeRequest:  addField("x", 1),
+					BeforeRequests: []func(echo.Context, *slog.Logger) *slog.Logger{addField("x", 2), addField("user_id", 3)},
+					AfterRequest:   addField("y", 1),
+					AfterRequests:  []func(echo.Context, *slog.Logger) *slog.Logger{addField("y", 2), addField("tenant_id", 4)},
+				},
+			})
+			e.GET("/", func(c echo.Context) error {
+				return c.String(200, "")
+			})
+			Expect(Serve(e, GetRequest("/"))).To(HaveResponseCode(200))
+			Expect(logHook.LastRecord().AttrMap()).To(And(
+				HaveKeyWithValue("x", BeEquivalentTo(2)),
+				HaveKeyWithValue("user_id", BeEquivalentTo(3)),
+				HaveKeyWithValue("y", BeEquivalentTo(2)),
+				HaveKeyWithValue("tenant_id", BeEquivalentTo(4)),
+			))
+		})
 		It("skips logging if AfterRequest returns a null logger", func() {
 			e = api.New(api.Config{
 				Logger: logger,
@@ -312,6 +584,24 @@ var _ = Describe("API", func() {
 			Expect(rr).To(HaveResponseCode(204))
 			Expect(rr.Body.String()).To(BeEmpty())
 		})
+		It("writes headers from the error", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(429, "slow_down").WithHeader("Retry-After", "30")
+			})
+			rr := Serve(e, GetRequest("/test"))
+			Expect(rr).To(HaveResponseCode(429))
+			Expect(rr).To(HaveHeader("Retry-After", Equal("30")))
+			Expect(rr).To(HaveJsonBody(Not(HaveKey("headers"))))
+		})
+		It("writes headers from the error for no-content responses", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.Error{HTTPStatus: 304, ErrorCode: "not_modified", Headers: map[string]string{"ETag": `"abc"`}}
+			})
+			rr := Serve(e, GetRequest("/test"))
+			Expect(rr).To(HaveResponseCode(304))
+			Expect(rr).To(HaveHeader("ETag", Equal(`"abc"`)))
+			Expect(rr.Body.String()).To(BeEmpty())
+		})
 		It("does not include a body for HEAD requests", func() {
 			e.HEAD("/test", func(c echo.Context) error {
 				return api.NewError(429, "hello_teapot")
@@ -321,6 +611,51 @@ var _ = Describe("API", func() {
 			Expect(rr).To(HaveResponseCode(429))
 			Expect(rr.Body.String()).To(BeEmpty())
 		})
+		It("renders errors as XML if requested", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(429, "hello_teapot", errors.New("orig"))
+			})
+			rr := Serve(e, GetRequest("/test", SetReqHeader("Accept", "application/xml")))
+			Expect(rr).To(HaveResponseCode(429))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/xml"))
+			Expect(rr.Body.String()).To(HaveSuffix(
+				"<error><http_status>429</http_status><error_code>hello_teapot</error_code>" +
+					"<message>Too Many Requests</message><original>orig</original></error>"))
+		})
+		It("renders errors as text if requested", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(429, "hello_teapot")
+			})
+			rr := Serve(e, GetRequest("/test", SetReqHeader("Accept", "application/json;q=0.5, text/plain;q=0.9")))
+			Expect(rr).To(HaveResponseCode(429))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+			Expect(rr.Body.String()).To(Equal("hello_teapot: [429] Too Many Requests"))
+		})
+		It("renders errors in the accepted format with the highest quality", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(429, "hello_teapot")
+			})
+			rr := Serve(e, GetRequest("/test", SetReqHeader("Accept", "text/plain;q=0.9, application/json")))
+			Expect(rr).To(HaveResponseCode(429))
+			Expect(rr).To(HaveJsonBody(HaveKeyWithValue("error_code", "hello_teapot")))
+
+			rr = Serve(e, GetRequest("/test", SetReqHeader("Accept", "text/plain;q=0.5, application/xml;q=0.5")))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+
+			rr = Serve(e, GetRequest("/test", SetReqHeader("Accept", "application/json;q=0, text/plain;q=0.1")))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+
+			rr = Serve(e, GetRequest("/test", SetReqHeader("Accept", "text/plain;q=0")))
+			Expect(rr).To(HaveJsonBody(HaveKeyWithValue("error_code", "hello_teapot")))
+		})
+		It("renders errors as JSON for unsupported Accept values", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(429, "hello_teapot")
+			})
+			rr := Serve(e, GetRequest("/test", SetReqHeader("Accept", "image/png")))
+			Expect(rr).To(HaveResponseCode(429))
+			Expect(rr).To(HaveJsonBody(HaveKeyWithValue("error_code", "hello_teapot")))
+		})
 		It("adapts echo errors", func() {
 			e.GET("/test", func(c echo.Context) error {
 				return echo.NewHTTPError(428, "echo msg")
@@ -345,6 +680,153 @@ var _ = Describe("API", func() {
 				HaveKeyWithValue("message", BeEquivalentTo("apiparams msg")),
 			)))
 		})
+		It("can convert apiparams errors in handlers, so they can be augmented", func() {
+			e.GET("/test", func(c echo.Context) error {
+				apiErr := api.FromApiparamsError(apiparams.NewHTTPError(422, "bad param"))
+				apiErr.ErrorCode = "bad_widget"
+				return apiErr
+			})
+			rr := Serve(e, GetRequest("/test"))
+			Expect(rr).To(HaveResponseCode(422))
+			Expect(rr).To(HaveJsonBody(And(
+				HaveKeyWithValue("error_code", "bad_widget"),
+				HaveKeyWithValue("message", "bad param"),
+			)))
+		})
+		It("can convert echo errors", func() {
+			apiErr := api.FromEchoError(echo.NewHTTPError(418, "short and stout"))
+			Expect(apiErr.HTTPStatus).To(Equal(418))
+			Expect(apiErr.ErrorCode).To(Equal("echo"))
+			Expect(apiErr.Message).To(Equal("short and stout"))
+		})
+	})
+
+	Describe("ErrorCatalog", func() {
+		var catalog *api.ErrorCatalog
+
+		BeforeEach(func() {
+			catalog = api.NewErrorCatalog()
+		})
+
+		It("creates errors from definitions", func() {
+			notFound := catalog.Define(404, "not_found", "")
+			conflict := catalog.Define(409, "already_exists", "Resource already exists")
+			Expect(notFound.New()).To(Equal(api.Error{HTTPStatus: 404, ErrorCode: "not_found", Message: "Not Found"}))
+			Expect(conflict.Newf("%s exists", "user")).To(Equal(api.Error{HTTPStatus: 409, ErrorCode: "already_exists", Message: "user exists"}))
+			orig := errors.New("orig")
+			Expect(conflict.New(orig).Original).To(BeIdenticalTo(orig))
+		})
+
+		It("can look up definitions", func() {
+			notFound := catalog.Define(404, "not_found", "")
+			catalog.Define(409, "already_exists", "")
+			def, ok := catalog.Lookup("not_found")
+			Expect(ok).To(BeTrue())
+			Expect(def).To(Equal(notFound))
+			_, ok = catalog.Lookup("nope")
+			Expect(ok).To(BeFalse())
+			Expect(catalog.Defs()).To(HaveLen(2))
+			Expect(catalog.Defs()[0].Code).To(Equal("not_found"))
+		})
+
+		It("can check if an error uses a definition", func() {
+			notFound := catalog.Define(404, "not_found", "")
+			conflict := catalog.Define(409, "already_exists", "")
+			err := fmt.Errorf("wrapped: %w", notFound.New())
+			Expect(notFound.Is(err)).To(BeTrue())
+			Expect(conflict.Is(err)).To(BeFalse())
+			Expect(notFound.Is(errors.New("x"))).To(BeFalse())
+		})
+
+		It("panics if a code is defined twice", func() {
+			catalog.Define(404, "not_found", "")
+			Expect(func() { catalog.Define(410, "not_found", "") }).To(PanicWith(ContainSubstring(`"not_found" is already defined`)))
+		})
+
+		It("renders catalog errors through the error handler", func() {
+			notFound := catalog.Define(404, "not_found", "")
+			e.GET("/test", func(c echo.Context) error {
+				return notFound.Newf("user not found")
+			})
+			rr := Serve(e, GetRequest("/test"))
+			Expect(rr).To(HaveResponseCode(404))
+			Expect(rr).To(HaveJsonBody(And(
+				HaveKeyWithValue("error_code", "not_found"),
+				HaveKeyWithValue("message", "user not found"),
+			)))
+		})
+	})
+
+	Describe("CORS", func() {
+		BeforeEach(func() {
+			e = api.New(api.Config{Logger: logger, CorsOrigins: []string{"https://example.com"}})
+		})
+
+		corsReq := func(path string) *http.Request {
+			return GetRequest(path, SetReqHeader("Origin", "https://example.com"))
+		}
+
+		It("adds CORS headers to successful responses", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			rr := Serve(e, corsReq("/test"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Origin", Equal("https://example.com")))
+		})
+		It("adds CORS headers to api.Error responses", func() {
+			e.GET("/test", func(c echo.Context) error {
+				return api.NewError(403, "forbidden")
+			})
+			rr := Serve(e, corsReq("/test"))
+			Expect(rr).To(HaveResponseCode(403))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Origin", Equal("https://example.com")))
+		})
+		It("adds CORS headers to responses from panicking handlers", func() {
+			e.GET("/test", func(c echo.Context) error {
+				panic("hello")
+			})
+			rr := Serve(e, corsReq("/test"))
+			Expect(rr).To(HaveResponseCode(500))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Origin", Equal("https://example.com")))
+		})
+		It("adds CORS headers to not found responses", func() {
+			rr := Serve(e, corsReq("/missing"))
+			Expect(rr).To(HaveResponseCode(404))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Origin", Equal("https://example.com")))
+		})
+		It("logs preflight requests", func() {
+			req := NewRequest("OPTIONS", "/test", nil,
+				SetReqHeader("Origin", "https://example.com"),
+				SetReqHeader("Access-Control-Request-Method", "POST"))
+			rr := Serve(e, req)
+			Expect(rr).To(HaveResponseCode(204))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Origin", Equal("https://example.com")))
+			Expect(logHook.Records()).To(HaveLen(1))
+			Expect(logHook.Records()[0].AttrMap()).To(HaveKeyWithValue("cors_preflight", true))
+		})
+		It("does not tag other requests as preflight", func() {
+			Serve(e, NewRequest("OPTIONS", "/test", nil))
+			Serve(e, corsReq("/test"))
+			Expect(logHook.Records()).To(HaveLen(2))
+			Expect(logHook.Records()[0].AttrMap()).ToNot(HaveKey("cors_preflight"))
+			Expect(logHook.Records()[1].AttrMap()).ToNot(HaveKey("cors_preflight"))
+		})
+		It("can set the preflight max age and allowed headers", func() {
+			e = api.New(api.Config{
+				Logger:           logger,
+				CorsOrigins:      []string{"https://example.com"},
+				CorsMaxAge:       600,
+				CorsAllowHeaders: []string{"Content-Type", "X-Custom"},
+			})
+			req := NewRequest("OPTIONS", "/test", nil,
+				SetReqHeader("Origin", "https://example.com"),
+				SetReqHeader("Access-Control-Request-Method", "POST"))
+			rr := Serve(e, req)
+			Expect(rr).To(HaveResponseCode(204))
+			Expect(rr).To(HaveHeader("Access-Control-Max-Age", Equal("600")))
+			Expect(rr).To(HaveHeader("Access-Control-Allow-Headers", Equal("Content-Type,X-Custom")))
+		})
 	})
 
 	Describe("adapting to standard context", func() {
@@ -368,6 +850,213 @@ var _ = Describe("API", func() {
 		})
 	})
 
+	Describe("MaxRequestLineMiddleware", func() {
+		BeforeEach(func() {
+			e.Use(api.MaxRequestLineMiddleware(api.MaxRequestLineConfig{MaxURILength: 30, MaxQueryLength: 10}))
+			e.GET("/*", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+		})
+
+		It("414s if the query is too long", func() {
+			resp := Serve(e, GetRequest("/?q="+strings.Repeat("x", 10)))
+			Expect(resp).To(HaveResponseCode(414))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("error_code", "uri_too_long")))
+			Expect(Serve(e, GetRequest("/?q=xxx"))).To(HaveResponseCode(200))
+		})
+
+		It("414s if the uri is too long", func() {
+			Expect(Serve(e, GetRequest("/"+strings.Repeat("x", 30)))).To(HaveResponseCode(414))
+			Expect(Serve(e, GetRequest("/"+strings.Repeat("x", 20)))).To(HaveResponseCode(200))
+		})
+
+		It("uses defaults", func() {
+			e := api.New(api.Config{Logger: logger})
+			e.Use(api.MaxRequestLineMiddleware(api.MaxRequestLineConfig{}))
+			e.GET("/", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			Expect(Serve(e, GetRequest("/?q="+strings.Repeat("x", api.DefaultMaxQueryLength)))).To(HaveResponseCode(414))
+			Expect(Serve(e, GetRequest("/?q="+strings.Repeat("x", 100)))).To(HaveResponseCode(200))
+		})
+	})
+
+	Describe("TimeoutMiddleware", func() {
+		BeforeEach(func() {
+			e.Use(api.TimeoutMiddleware(20 * time.Millisecond))
+		})
+
+		It("503s if the handler does not finish before the deadline", func() {
+			e.GET("/", func(c echo.Context) error {
+				time.Sleep(40 * time.Millisecond)
+				return nil
+			})
+			resp := Serve(e, GetRequest("/"))
+			Expect(resp).To(HaveResponseCode(503))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("error_code", "request_timeout")))
+		})
+
+		It("503s if the handler returns a context error", func() {
+			e.GET("/", func(c echo.Context) error {
+				<-c.Request().Context().Done()
+				return c.Request().Context().Err()
+			})
+			resp := Serve(e, GetRequest("/"))
+			Expect(resp).To(HaveResponseCode(503))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("error_code", "request_timeout")))
+		})
+
+		It("returns the response of a handler that finishes in time, and cancels its context", func() {
+			var ctx context.Context
+			e.GET("/", func(c echo.Context) error {
+				ctx = c.Request().Context()
+				_, hasDeadline := ctx.Deadline()
+				Expect(hasDeadline).To(BeTrue())
+				Expect(ctx.Err()).ToNot(HaveOccurred())
+				return c.String(200, "ok")
+			})
+			resp := Serve(e, GetRequest("/"))
+			Expect(resp).To(HaveResponseCode(200))
+			Expect(resp.Body.String()).To(Equal("ok"))
+			Expect(ctx.Err()).To(MatchError(context.Canceled))
+		})
+
+		It("does not replace a response the handler already wrote", func() {
+			e.GET("/", func(c echo.Context) error {
+				time.Sleep(40 * time.Millisecond)
+				return c.String(200, "late")
+			})
+			Expect(Serve(e, GetRequest("/"))).To(HaveResponseCode(200))
+		})
+	})
+
+	Describe("MetricsMiddleware", func() {
+		var collector *api.MemoryCollector
+
+		BeforeEach(func() {
+			collector = api.NewMemoryCollector(10*time.Millisecond, time.Second)
+			e.Use(api.MetricsMiddleware(api.MetricsConfig{Collector: collector}))
+			e.GET("/users/:id", func(c echo.Context) error {
+				if c.Param("id") == "bad" {
+					return errors.New("oh no")
+				}
+				return c.String(200, "ok")
+			})
+		})
+
+		It("records the count and latency of successful and failed requests", func() {
+			Expect(Serve(e, GetRequest("/users/1"))).To(HaveResponseCode(200))
+			Expect(Serve(e, GetRequest("/users/2"))).To(HaveResponseCode(200))
+			Expect(Serve(e, GetRequest("/users/bad"))).To(HaveResponseCode(500))
+
+			ok := api.RequestMetricLabels{Method: "GET", Route: "/users/:id", StatusClass: "2xx"}
+			failed := api.RequestMetricLabels{Method: "GET", Route: "/users/:id", StatusClass: "5xx"}
+			Expect(collector.Labels()).To(ConsistOf(ok, failed))
+			Expect(collector.Count(ok)).To(Equal(2))
+			Expect(collector.Count(failed)).To(Equal(1))
+			Expect(collector.BucketCounts(ok)).To(Equal([]int{2, 2, 2}))
+			Expect(collector.BucketCounts(failed)).To(Equal([]int{1, 1, 1}))
+		})
+
+		It("uses the status an error is rendered with", func() {
+			e.GET("/teapot", func(c echo.Context) error {
+				return api.NewError(418, "teapot")
+			})
+			Expect(Serve(e, GetRequest("/teapot"))).To(HaveResponseCode(418))
+			Expect(collector.Count(api.RequestMetricLabels{Method: "GET", Route: "/teapot", StatusClass: "4xx"})).To(Equal(1))
+		})
+
+		It("records panics as a 500", func() {
+			e.GET("/panic", func(c echo.Context) error {
+				panic("oh no")
+			})
+			Expect(Serve(e, GetRequest("/panic"))).To(HaveResponseCode(500))
+			Expect(collector.Count(api.RequestMetricLabels{Method: "GET", Route: "/panic", StatusClass: "5xx"})).To(Equal(1))
+		})
+
+		It("puts latencies into the right buckets", func() {
+			e.GET("/slow", func(c echo.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/slow"))).To(HaveResponseCode(204))
+			labels := api.RequestMetricLabels{Method: "GET", Route: "/slow", StatusClass: "2xx"}
+			Expect(collector.BucketCounts(labels)).To(Equal([]int{0, 1, 1}))
+			Expect(collector.Sum(labels)).To(BeNumerically(">=", 20*time.Millisecond))
+		})
+
+		It("labels requests that do not match a route", func() {
+			Expect(Serve(e, GetRequest("/nope/123"))).To(HaveResponseCode(404))
+			Expect(collector.Labels()).To(ConsistOf(api.RequestMetricLabels{Method: "GET", Route: api.UnmatchedRoute, StatusClass: "4xx"}))
+		})
+
+		It("can skip requests", func() {
+			e := api.New(api.Config{Logger: logger})
+			e.Use(api.MetricsMiddleware(api.MetricsConfig{
+				Collector: collector,
+				Skipper:   func(c echo.Context) bool { return c.Path() == api.HealthPath },
+			}))
+			Expect(Serve(e, GetRequest(api.HealthPath))).To(HaveResponseCode(200))
+			Expect(collector.Labels()).To(BeEmpty())
+		})
+	})
+
+	Describe("outbound trace headers", func() {
+		const uuidTrace = "0af76519-16cd-43dd-8448-eb211c80319c"
+
+		It("sets the trace id and traceparent from the context", func() {
+			ctx := context.WithValue(context.Background(), logctx.RequestTraceIdKey, uuidTrace)
+			req, err := http.NewRequest("GET", "http://localhost", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.SetTraceHeaders(ctx, req)
+			Expect(req.Header.Get(api.TraceIdHeader)).To(Equal(uuidTrace))
+			Expect(req.Header.Get(api.TraceParentHeader)).To(MatchRegexp(
+				`^00-0af7651916cd43dd8448eb211c80319c-[0-9a-f]{16}-01$`))
+		})
+
+		It("only sets the trace id if it cannot be used in a traceparent", func() {
+			ctx := context.WithValue(context.Background(), logctx.JobTraceIdKey, "abc")
+			req, err := http.NewRequest("GET", "http://localhost", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.SetTraceHeaders(ctx, req)
+			Expect(req.Header.Get(api.TraceIdHeader)).To(Equal("abc"))
+			Expect(req.Header.Get(api.TraceParentHeader)).To(BeEmpty())
+		})
+
+		It("does not set headers that are already set, or if there is no trace id", func() {
+			req, err := http.NewRequest("GET", "http://localhost", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.SetTraceHeaders(context.Background(), req)
+			Expect(req.Header).To(BeEmpty())
+
+			ctx := context.WithValue(context.Background(), logctx.RequestTraceIdKey, uuidTrace)
+			req.Header.Set(api.TraceIdHeader, "mine")
+			api.SetTraceHeaders(ctx, req)
+			Expect(req.Header.Get(api.TraceIdHeader)).To(Equal("mine"))
+		})
+
+		It("can propagate the trace id from an endpoint with a transport", func() {
+			var got http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header
+			}))
+			defer server.Close()
+			client := &http.Client{Transport: api.NewTraceTransport(nil)}
+			e.GET("/endpoint", func(c echo.Context) error {
+				req, err := http.NewRequestWithContext(api.StdContext(c), "GET", server.URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				_ = resp.Body.Close()
+				Expect(req.Header).To(BeEmpty())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/endpoint", SetReqHeader(api.TraceIdHeader, uuidTrace)))).To(HaveResponseCode(204))
+			Expect(got.Get(api.TraceIdHeader)).To(Equal(uuidTrace))
+			Expect(got.Get(api.TraceParentHeader)).To(HavePrefix("00-0af7651916cd43dd8448eb211c80319c-"))
+		})
+	})
+
 	Describe("CacheControl", func() {
 		It("adds a cache-control header", func() {
 			e.POST("/endpoint", func(c echo.Context) error {
@@ -426,6 +1115,17 @@ var _ = Describe("API", func() {
 				HaveKeyWithValue("debug_response_body", ContainSubstring("ok")),
 			))
 		})
+		It("does not dump sensitive headers", func() {
+			e = api.New(api.Config{Logger: logger, SensitiveHeaders: []string{"Foo"}})
+			e.Use(api.DebugMiddleware(api.DebugMiddlewareConfig{Enabled: true, DumpRequestHeaders: true}))
+			e.GET("/endpoint", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			Serve(e, NewRequest("GET", "/endpoint", nil, SetReqHeader("Foo", "x"), SetReqHeader("Bar", "y")))
+			Expect(logHook.Records()[0].AttrMap()).To(
+				HaveKeyWithValue("debug_request_headers", And(HaveKey("Bar"), Not(HaveKey("Foo")))),
+			)
+		})
 		It("can print memory stats every n requests", func() {
 			e.Use(api.DebugMiddleware(api.DebugMiddlewareConfig{Enabled: true, DumpMemoryEvery: 2}))
 			e.GET("/endpoint", func(c echo.Context) error {
@@ -441,5 +1141,18 @@ var _ = Describe("API", func() {
 			Expect(logHook.Records()[2].AttrMap()).To(HaveKey("memory_sys"))
 			Expect(logHook.Records()[3].Record.Message).To(Equal("request_finished"))
 		})
+		It("can print memory stats at most once per duration", func() {
+			e.Use(api.DebugMiddleware(api.DebugMiddlewareConfig{Enabled: true, DumpMemoryEveryDuration: time.Hour}))
+			e.GET("/endpoint", func(c echo.Context) error {
+				return c.String(200, "ok")
+			})
+			Serve(e, GetRequest("/endpoint"))
+			Serve(e, GetRequest("/endpoint"))
+			Expect(logHook.Records()).To(HaveLen(4))
+			Expect(logHook.Records()[0].Record.Message).To(Equal("request_debug"))
+			Expect(logHook.Records()[0].AttrMap()).To(HaveKey("memory_sys"))
+			Expect(logHook.Records()[2].Record.Message).To(Equal("request_debug"))
+			Expect(logHook.Records()[2].AttrMap()).ToNot(HaveKey("memory_sys"))
+		})
 	})
 })
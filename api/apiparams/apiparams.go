@@ -36,20 +36,27 @@ func BindAndValidate(adapter Adapter, paramsStructPtr interface{}, handlerArgs .
 type Handler struct {
 	reflector reflector
 	binder    binder
+	opts      handlerOptions
 }
 
 // New returns a new Handler.
 // In general, callers should use apiparams.BindAndValidate,
 // rather than dealing with Handler explicitly,
 // but it is provided here in case callers only want binding or validating for some reason.
+// Any Options in handlerArgs customize the Handler; see Option.
 func New(adapter Adapter, paramsStructPtr interface{}, handlerArgs ...interface{}) Handler {
+	opts, handlerArgs := splitOptions(handlerArgs)
 	ref := newReflector(paramsStructPtr)
 	req := adapter.Request(handlerArgs)
-	binder := newBinder(ref, req, adapter.RouteParamNames(handlerArgs), adapter.RouteParamValues(handlerArgs))
-	ph := Handler{ref, binder}
+	binder := newBinder(ref, req, adapter.RouteParamNames(handlerArgs), adapter.RouteParamValues(handlerArgs), opts)
+	ph := Handler{ref, binder, opts}
 	for _, def := range defaultCustomTypes {
 		ph.registerCustomType(def)
 	}
+	// Register these after the global types, so they take precedence.
+	for _, def := range opts.customTypes {
+		ph.registerCustomType(def)
+	}
 	return ph
 }
 
@@ -58,31 +65,75 @@ func (ph Handler) BindFromAll() HTTPError {
 	return ph.binder.BindFromAll()
 }
 
+// Provided returns true if the client sent a value for the struct field
+// with the given name, from any source (path, query, header, form, or body).
+// Use a dotted path for fields of nested structs, like "Note.Content".
+// Unlike checking for a zero value, this can tell an explicit {"count":0} from an omitted count.
+// Fields set only from defaults are not provided.
+// Only meaningful after BindFromAll.
+func (ph Handler) Provided(fieldName string) bool {
+	return ph.binder.provided.Provided(fieldName)
+}
+
 // Validate calls go-validate.Validate on the (bound) parameter struct,
 // and returns an HTTPError if there were validation errors,
 // or NoHTTPError if there were none.
+// If the struct is valid, fields validated with enum are rewritten
+// to the casing of the matching choice (see validator.NormalizeEnums).
 func (ph Handler) Validate() HTTPError {
-	if err := validator.Validate(ph.reflector.Pointer()); err != nil {
-		errMap, ok := err.(validator.ErrorMap)
-		if !ok {
-			return NewHTTPError(http.StatusUnprocessableEntity, err.Error())
-		}
-		errs := ph.formatErrors(errMap)
-		return httpError{http.StatusUnprocessableEntity, errs}
+	validate := validator.Validate
+	if ph.opts.now != nil {
+		validate = validator.NewRegistry(ph.opts.now).Validate
+	}
+	if err := validate(ph.reflector.Pointer()); err != nil {
+		return validationError(ph.reflector, err, ph.binder.boundParamNames, ph.opts.translateMessage)
 	}
+	validator.NormalizeEnums(ph.reflector.Pointer())
 	return nil
 }
 
-// Format a validator.ErrorMap into an array of error strings.
-func (ph Handler) formatErrors(errorMap validator.ErrorMap) []string {
-	var lines = make([]string, 0, len(errorMap))
+// validationError converts an error from validator.Validate into a 422 HTTPError,
+// with a message and FieldError for each field error.
+// If translate is not nil, it is used to replace the message of each field error.
+func validationError(r reflector, err error, boundNames map[string]string, translate MessageTranslator) HTTPError {
+	errMap, ok := err.(validator.ErrorMap)
+	if !ok {
+		return NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	fieldErrs := fieldErrors(r, errMap, boundNames, translate)
+	errs := make([]string, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		errs = append(errs, fmt.Sprintf("%s: %s", fe.Param, fe.Message))
+	}
+	return httpError{http.StatusUnprocessableEntity, errs, fieldErrs}
+}
+
+// Convert a validator.ErrorMap into a FieldError for each error,
+// using parameter names rather than struct field names,
+// and the validatemsg struct tag of the field as the message, if it has one.
+// The message is then passed through translate, if it is not nil.
+func fieldErrors(r reflector, errorMap validator.ErrorMap, boundNames map[string]string, translate MessageTranslator) []FieldError {
+	var result = make([]FieldError, 0, len(errorMap))
 	for fieldName, errorArray := range errorMap {
+		paramName := r.MapFieldNameToParamName(fieldName, boundNames)
+		customMessage, hasCustomMessage := r.ValidateMessageFor(fieldName)
 		for _, err := range errorArray {
-			line := fmt.Sprintf("%s: %s", ph.reflector.MapFieldNameToParamName(fieldName), err.Error())
-			lines = append(lines, line)
+			msg := err.Error()
+			if hasCustomMessage {
+				msg = customMessage
+			}
+			validatorName := validator.ValidatorName(err)
+			if translate != nil {
+				msg = translate(paramName, validatorName, msg)
+			}
+			result = append(result, FieldError{
+				Param:     paramName,
+				Validator: validatorName,
+				Message:   msg,
+			})
 		}
 	}
-	return lines
+	return result
 }
 
 // RegisterCustomType registers a custom type definition onto this handler.
@@ -141,8 +192,52 @@ var defaultCustomTypes = make([]customTypeDef, 0, 2)
 // so that other types can be used in API parameters.
 // Using this module-level method makes these custom types available to all Handlers
 // (all calls of apiparams.BindAndValidate).
+//
+// Panic if a definition for the same type is already registered,
+// since it is a programming error and would make binding order-dependent.
 func RegisterCustomType(def CustomTypeDef) {
-	defaultCustomTypes = append(defaultCustomTypes, def.expand())
+	expanded := def.expand()
+	for _, existing := range defaultCustomTypes {
+		if existing.Type == expanded.Type {
+			panic(fmt.Sprintf("apiparams: custom type %v is already registered", expanded.Type))
+		}
+	}
+	defaultCustomTypes = append(defaultCustomTypes, expanded)
+}
+
+// OverrideCustomType registers a custom type definition,
+// replacing any existing definition for the same type.
+// Use this to change the behavior of a built-in custom type,
+// like parsing time.Time with a format other than RFC3339.
+func OverrideCustomType(def CustomTypeDef) {
+	expanded := def.expand()
+	for i, existing := range defaultCustomTypes {
+		if existing.Type == expanded.Type {
+			defaultCustomTypes[i] = expanded
+			return
+		}
+	}
+	defaultCustomTypes = append(defaultCustomTypes, expanded)
+}
+
+// DeregisterCustomType removes the custom type definition for the type of value,
+// if one is registered.
+// After this, fields of the type cannot be bound unless a definition is registered again.
+func DeregisterCustomType(value interface{}) {
+	t := reflect.TypeOf(value)
+	for i, existing := range defaultCustomTypes {
+		if existing.Type == t {
+			defaultCustomTypes = append(defaultCustomTypes[:i:i], defaultCustomTypes[i+1:]...)
+			return
+		}
+	}
+}
+
+// RegisterCustomTypes calls RegisterCustomType for each definition.
+func RegisterCustomTypes(defs ...CustomTypeDef) {
+	for _, def := range defs {
+		RegisterCustomType(def)
+	}
 }
 
 func init() {
@@ -154,5 +249,22 @@ func init() {
 				return reflect.ValueOf(&v), err
 			}
 			return reflect.ValueOf(v), err
-		}})
+		},
+		Defaulter: func(value string) string {
+			if value == "now" {
+				return time.Now().Format(time.RFC3339Nano)
+			}
+			return value
+		},
+	})
+	RegisterCustomType(CustomTypeDef{
+		Value: time.Duration(0),
+		Parser: func(value string, usePtr bool) (reflect.Value, error) {
+			v, err := time.ParseDuration(value)
+			if usePtr {
+				return reflect.ValueOf(&v), err
+			}
+			return reflect.ValueOf(v), err
+		},
+	})
 }
@@ -1,6 +1,8 @@
 package apiparams_test
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/lithictech/go-aperitif/v2/api/apiparams"
@@ -9,6 +11,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/rgalanakis/golangal"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -67,9 +71,9 @@ var _ = Describe("apiparams package", func() {
 
 	type emptyHandlerParams struct{}
 
-	shouldFailHandler := func(paramsPtr interface{}) echo.HandlerFunc {
+	shouldFailHandler := func(paramsPtr interface{}, opts ...interface{}) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if err := apiparams.BindAndValidate(ad, paramsPtr, c); err != nil {
+			if err := apiparams.BindAndValidate(ad, paramsPtr, append([]interface{}{c}, opts...)...); err != nil {
 				return echo.NewHTTPError(err.Code(), err.Error())
 			}
 			fmt.Println("Unreachable handler was reached...")
@@ -85,6 +89,49 @@ var _ = Describe("apiparams package", func() {
 		Expect(resp).To(HaveResponseCode(415))
 	})
 
+	Describe("body decoders", func() {
+		type handlerParams struct {
+			Name string `json:"name" xml:"name"`
+			Age  int    `json:"age" xml:"age"`
+		}
+
+		BeforeEach(func() {
+			apiparams.RegisterBodyDecoder("application/xml", func(body io.Reader, ptr interface{}) error {
+				return xml.NewDecoder(body).Decode(ptr)
+			})
+			DeferCleanup(apiparams.DeregisterBodyDecoder, "application/xml")
+		})
+
+		It("decodes bodies with a registered decoder", func() {
+			hp := handlerParams{}
+			group.POST("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+				return c.NoContent(204)
+			})
+			body := `<params><name>jane</name><age>5</age></params>`
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(body), SetReqHeader("Content-Type", "application/xml; charset=utf-8")))
+			Expect(resp).To(HaveResponseCode(204))
+			Expect(hp).To(Equal(handlerParams{Name: "jane", Age: 5}))
+		})
+
+		It("400s if the decoder errors", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`<params>`), SetReqHeader("Content-Type", "application/xml")))
+			Expect(resp).To(HaveResponseCode(400))
+		})
+
+		It("415s for content types without a decoder", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`x`), SetReqHeader("Content-Type", "text/plain")))
+			Expect(resp).To(HaveResponseCode(415))
+		})
+
+		It("panics if a decoder is already registered for the content type", func() {
+			Expect(func() { apiparams.RegisterBodyDecoder("application/json", nil) }).To(
+				PanicWith(ContainSubstring("body decoder for application/json is already registered")))
+		})
+	})
+
 	Context("binds the parameter struct", func() {
 
 		It("to query parameters", func() {
@@ -228,6 +275,41 @@ var _ = Describe("apiparams package", func() {
 			Expect(resp).To(HaveResponseCode(200))
 		})
 
+		It("binds empty query values to pointer fields", func() {
+			type handlerParams struct {
+				S *string `json:"s"`
+			}
+			hp := handlerParams{}
+			group.GET("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/foo?s="))).To(HaveResponseCode(204))
+			Expect(hp.S).ToNot(BeNil())
+			Expect(*hp.S).To(Equal(""))
+		})
+
+		It("can treat empty query and form values as unset for pointer fields", func() {
+			type handlerParams struct {
+				S  *string `json:"s"`
+				I  *int    `json:"i"`
+				F  *string `form:"f"`
+				NP string  `json:"np" default:"x"`
+			}
+			hp := handlerParams{}
+			group.POST("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.EmptyAsUnset())).To(Succeed())
+				return c.NoContent(204)
+			})
+			resp := Serve(e, NewRequest("POST", "/foo?s=&i=&np=", []byte("f="),
+				SetReqHeader("Content-Type", "application/x-www-form-urlencoded")))
+			Expect(resp).To(HaveResponseCode(204))
+			Expect(hp.S).To(BeNil())
+			Expect(hp.I).To(BeNil())
+			Expect(hp.F).To(BeNil())
+			Expect(hp.NP).To(BeEmpty())
+		})
+
 		Describe("to all supported field types", func() {
 			qparams := strings.Join([]string{
 				"s=a",
@@ -239,6 +321,9 @@ var _ = Describe("apiparams package", func() {
 				"i=1",
 				"i32=1",
 				"i64=1",
+				"u=1",
+				"u32=1",
+				"u64=1",
 				"intslice=1",
 				"intslice=2",
 				"f32=1",
@@ -255,6 +340,9 @@ var _ = Describe("apiparams package", func() {
 					I        int       `json:"i"`
 					I64      int64     `json:"i64"`
 					I32      int32     `json:"i32"`
+					U        uint      `json:"u"`
+					U64      uint64    `json:"u64"`
+					U32      uint32    `json:"u32"`
 					IntSlice []int     `json:"intslice"`
 					F64      float64   `json:"f64"`
 					F32      float32   `json:"f32"`
@@ -271,6 +359,9 @@ var _ = Describe("apiparams package", func() {
 						Expect(hp.I).To(Equal(1))
 						Expect(hp.I64).To(Equal(int64(1)))
 						Expect(hp.I32).To(Equal(int32(1)))
+						Expect(hp.U).To(Equal(uint(1)))
+						Expect(hp.U64).To(Equal(uint64(1)))
+						Expect(hp.U32).To(Equal(uint32(1)))
 						Expect(hp.IntSlice).To(Equal([]int{1, 2}))
 						Expect(hp.F64).To(Equal(float64(1)))
 						Expect(hp.F32).To(Equal(float32(1)))
@@ -290,6 +381,9 @@ var _ = Describe("apiparams package", func() {
 					I        *int       `json:"i"`
 					I64      *int64     `json:"i64"`
 					I32      *int32     `json:"i32"`
+					U        *uint      `json:"u"`
+					U64      *uint64    `json:"u64"`
+					U32      *uint32    `json:"u32"`
 					IntSlice *[]int     `json:"intslice"`
 					F64      *float64   `json:"f64"`
 					F32      *float32   `json:"f32"`
@@ -300,17 +394,15 @@ var _ = Describe("apiparams package", func() {
 					"/foo",
 					func(c echo.Context) error {
 						hp := handlerParams{}
-						//ss := make([]string, 0)
-						//hp.StrSlice = &ss
-						//is := make([]int, 0)
-						//hp.IntSlice = &is
-
 						Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
 						Expect(*hp.S).To(Equal("a"))
 						Expect(*hp.StrSlice).To(Equal([]string{"a", "b"}))
 						Expect(*hp.I).To(Equal(1))
 						Expect(*hp.I64).To(Equal(int64(1)))
 						Expect(*hp.I32).To(Equal(int32(1)))
+						Expect(*hp.U).To(Equal(uint(1)))
+						Expect(*hp.U64).To(Equal(uint64(1)))
+						Expect(*hp.U32).To(Equal(uint32(1)))
 						Expect(*hp.IntSlice).To(Equal([]int{1, 2}))
 						Expect(*hp.F64).To(Equal(float64(1)))
 						Expect(*hp.F32).To(Equal(float32(1)))
@@ -324,6 +416,52 @@ var _ = Describe("apiparams package", func() {
 				Expect(resp).To(HaveResponseCode(200))
 			})
 
+			It("when they are slices of any supported type", func() {
+				type handlerParams struct {
+					I64s  []int64      `query:"i64"`
+					Us    []uint       `query:"u"`
+					U32s  *[]uint32    `query:"u32"`
+					F64s  []float64    `query:"f64"`
+					Bs    []bool       `query:"b"`
+					IPtrs []*int       `query:"iptr"`
+					Ts    *[]time.Time `query:"t"`
+				}
+				Expect(apiparams.ValidateStruct(&handlerParams{})).To(Succeed())
+				group.GET(
+					"/foo",
+					func(c echo.Context) error {
+						hp := handlerParams{}
+						Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+						Expect(hp.I64s).To(Equal([]int64{1, -2}))
+						Expect(hp.Us).To(Equal([]uint{3}))
+						Expect(*hp.U32s).To(Equal([]uint32{4, 5}))
+						Expect(hp.F64s).To(Equal([]float64{1.5}))
+						Expect(hp.Bs).To(Equal([]bool{true, false}))
+						Expect(hp.IPtrs).To(HaveLen(1))
+						Expect(*hp.IPtrs[0]).To(Equal(6))
+						Expect(*hp.Ts).To(HaveLen(1))
+						return c.JSON(http.StatusOK, 1)
+					},
+				)
+				resp := Serve(e, GetRequest("/foo?i64=1&i64=-2&u=3&u32[]=4&u32[]=5&f64=1.5&b=true&b=false&iptr=6&t=2020-01-01T00:00:00Z"))
+				Expect(resp).To(HaveResponseCode(200))
+			})
+
+			It("appending to a pointer to a slice that is already set", func() {
+				type handlerParams struct {
+					IntSlice *[]int `query:"i"`
+				}
+				group.GET(
+					"/foo",
+					func(c echo.Context) error {
+						hp := handlerParams{IntSlice: &[]int{1}}
+						Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+						Expect(*hp.IntSlice).To(Equal([]int{1, 2, 3}))
+						return c.JSON(http.StatusOK, 1)
+					},
+				)
+				Expect(Serve(e, GetRequest("/foo?i=2&i=3"))).To(HaveResponseCode(200))
+			})
 		})
 
 		It("parses fields based on their path/query/header struct tag, rather than json, if provided", func() {
@@ -406,6 +544,103 @@ var _ = Describe("apiparams package", func() {
 					SetReqHeader("Content-Type", "application/x-www-form-urlencoded")))
 			Expect(resp).To(HaveResponseCode(200))
 		})
+
+		Describe("multipart forms", func() {
+			type handlerParams struct {
+				FormTag int    `form:"formTag"`
+				JSONTag string `json:"jsonTag"`
+			}
+			multipartBody := func(values map[string]string) ([]byte, string) {
+				buf := bytes.NewBuffer(nil)
+				w := multipart.NewWriter(buf)
+				for k, v := range values {
+					Expect(w.WriteField(k, v)).To(Succeed())
+				}
+				Expect(w.Close()).To(Succeed())
+				return buf.Bytes(), w.FormDataContentType()
+			}
+
+			It("binds form values", func() {
+				hp := handlerParams{}
+				group.POST("/foo", func(c echo.Context) error {
+					Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.MultipartMaxMemory(1024))).To(Succeed())
+					return c.NoContent(204)
+				})
+				body, ctype := multipartBody(map[string]string{"formTag": "123", "jsonTag": "abc"})
+				resp := Serve(e, NewRequest("POST", "/foo", body, SetReqHeader("Content-Type", ctype)))
+				Expect(resp).To(HaveResponseCode(204))
+				Expect(hp.FormTag).To(Equal(123))
+				Expect(hp.JSONTag).To(Equal("abc"))
+			})
+
+			It("binds uploaded files and array fields", func() {
+				type uploadParams struct {
+					Title       string                  `form:"title"`
+					Tags        []string                `form:"tags"`
+					Avatar      *multipart.FileHeader   `file:"avatar" validate:"nonzero"`
+					Attachments []*multipart.FileHeader `file:"attachments"`
+				}
+				hp := uploadParams{}
+				var content []byte
+				group.POST("/foo", func(c echo.Context) error {
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					f, err := hp.Avatar.Open()
+					Expect(err).ToNot(HaveOccurred())
+					defer f.Close()
+					content, err = io.ReadAll(f)
+					Expect(err).ToNot(HaveOccurred())
+					return c.NoContent(204)
+				})
+				buf := bytes.NewBuffer(nil)
+				w := multipart.NewWriter(buf)
+				Expect(w.WriteField("title", "hello")).To(Succeed())
+				Expect(w.WriteField("tags", "a")).To(Succeed())
+				Expect(w.WriteField("tags", "b")).To(Succeed())
+				for _, name := range []string{"avatar", "attachments", "attachments"} {
+					fw, err := w.CreateFormFile(name, name+".txt")
+					Expect(err).ToNot(HaveOccurred())
+					_, err = fw.Write([]byte("contents of " + name))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(w.Close()).To(Succeed())
+				resp := Serve(e, NewRequest("POST", "/foo", buf.Bytes(), SetReqHeader("Content-Type", w.FormDataContentType())))
+				Expect(resp).To(HaveResponseCode(204))
+				Expect(hp.Title).To(Equal("hello"))
+				Expect(hp.Tags).To(Equal([]string{"a", "b"}))
+				Expect(hp.Avatar.Filename).To(Equal("avatar.txt"))
+				Expect(string(content)).To(Equal("contents of avatar"))
+				Expect(hp.Attachments).To(HaveLen(2))
+				Expect(apiparams.ValidateStruct(&uploadParams{})).To(Succeed())
+			})
+
+			It("does not set file fields from other sources", func() {
+				type uploadParams struct {
+					Avatar *multipart.FileHeader `json:"avatar" validate:"nonzero"`
+				}
+				group.POST("/foo", shouldFailHandler(&uploadParams{}))
+				body, ctype := multipartBody(map[string]string{"avatar": "abc"})
+				resp := Serve(e, NewRequest("POST", "/foo?avatar=x", body, SetReqHeader("Content-Type", ctype)))
+				Expect(resp).To(HaveResponseCode(422))
+			})
+
+			It("errors in ValidateStruct for file fields of other types", func() {
+				type uploadParams struct {
+					Avatar string `file:"avatar"`
+				}
+				Expect(apiparams.ValidateStruct(&uploadParams{})).To(MatchError(ContainSubstring("file params must be")))
+			})
+
+			It("413s if the body is over the request's body limit", func() {
+				group.POST("/foo", func(c echo.Context) error {
+					c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, 10)
+					err := apiparams.BindAndValidate(ad, &handlerParams{}, c)
+					return echo.NewHTTPError(err.Code(), err.Error())
+				})
+				body, ctype := multipartBody(map[string]string{"jsonTag": strings.Repeat("x", 100)})
+				resp := Serve(e, NewRequest("POST", "/foo", body, SetReqHeader("Content-Type", ctype)))
+				Expect(resp).To(HaveResponseCode(413))
+			})
+		})
 	})
 
 	Describe("defaults", func() {
@@ -588,6 +823,18 @@ var _ = Describe("apiparams package", func() {
 		Expect(resp).To(HaveResponseCode(400))
 	})
 
+	It("returns a 400 if a negative number is given for an unsigned field", func() {
+		type handlerParams struct {
+			ID uint64 `json:"id"`
+		}
+		group.GET(
+			"/foo",
+			shouldFailHandler(&handlerParams{}),
+		)
+		resp := Serve(e, GetRequest("/foo?id=-1"))
+		Expect(resp).To(HaveResponseCode(400))
+	})
+
 	It("returns a 400 if form parameters are the wrong type", func() {
 		type handlerParams struct {
 			A int `json:"a"`
@@ -628,6 +875,342 @@ var _ = Describe("apiparams package", func() {
 		Expect(resp).To(HaveResponseCode(200))
 	})
 
+	Describe("LenientJSONNumbers", func() {
+		type item struct {
+			Qty int `json:"qty"`
+		}
+		type handlerParams struct {
+			ID     int       `json:"id"`
+			Price  *float64  `json:"price"`
+			Code   string    `json:"code"`
+			Items  []item    `json:"items"`
+			Quoted int64     `json:"quoted,string"`
+			At     time.Time `json:"at"`
+		}
+
+		It("coerces numeric strings and numbers in JSON bodies", func() {
+			hp := handlerParams{}
+			group.POST("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.LenientJSONNumbers())).To(Succeed())
+				return c.NoContent(204)
+			})
+			body := `{"id":"123","price":" 1.5","code":456,"items":[{"qty":"2"}],"quoted":"7","at":"2020-01-02T00:00:00Z"}`
+			Expect(Serve(e, NewRequest("POST", "/foo", []byte(body), JsonReq()))).To(HaveResponseCode(204))
+			Expect(hp.ID).To(Equal(123))
+			Expect(*hp.Price).To(Equal(1.5))
+			Expect(hp.Code).To(Equal("456"))
+			Expect(hp.Items).To(Equal([]item{{Qty: 2}}))
+			Expect(hp.Quoted).To(Equal(int64(7)))
+			Expect(hp.At.Year()).To(Equal(2020))
+		})
+
+		It("400s for strings that are not numeric", func() {
+			group.POST("/foo", func(c echo.Context) error {
+				err := apiparams.BindAndValidate(ad, &handlerParams{}, c, apiparams.LenientJSONNumbers())
+				return echo.NewHTTPError(err.Code(), err.Error())
+			})
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"id":"abc"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+			Expect(resp.Body.String()).To(ContainSubstring("Unmarshal type error"))
+		})
+
+		It("is strict by default", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"id":"123"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+		})
+	})
+
+	Describe("body fields", func() {
+		type item struct {
+			Name string `json:"name" validate:"min=1"`
+		}
+		type handlerParams struct {
+			ListID int    `path:"id"`
+			DryRun bool   `query:"dry_run"`
+			Items  []item `body:"items" validate:"min=1"`
+		}
+		var hp handlerParams
+		var handler apiparams.Handler
+		BeforeEach(func() {
+			hp = handlerParams{}
+			group.POST("/lists/:id/items", func(c echo.Context) error {
+				handler = apiparams.New(ad, &hp, c)
+				if err := handler.BindFromAll(); err != nil {
+					return echo.NewHTTPError(err.Code(), err.Error())
+				}
+				if err := handler.Validate(); err != nil {
+					return echo.NewHTTPError(err.Code(), err.Error())
+				}
+				return c.NoContent(200)
+			})
+		})
+
+		It("decodes the body into the field, and binds other params to sibling fields", func() {
+			body := []byte(`[{"name":"a"},{"name":"b"}]`)
+			resp := Serve(e, NewRequest("POST", "/lists/5/items?dry_run=true", body, JsonReq()))
+			Expect(resp).To(HaveResponseCode(200))
+			Expect(hp.ListID).To(Equal(5))
+			Expect(hp.DryRun).To(BeTrue())
+			Expect(hp.Items).To(Equal([]item{{"a"}, {"b"}}))
+			Expect(handler.Provided("Items")).To(BeTrue())
+			Expect(apiparams.ValidateStruct(&handlerParams{})).To(Succeed())
+		})
+
+		It("uses the body tag name in validation errors", func() {
+			resp := Serve(e, NewRequest("POST", "/lists/5/items", []byte(`[{"name":"a"},{"name":""}]`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp.Body.String()).To(ContainSubstring("items[1].name"))
+		})
+
+		It("errors like other bodies", func() {
+			resp := Serve(e, NewRequest("POST", "/lists/5/items", []byte(`{"name":"a"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+			Expect(resp.Body.String()).To(ContainSubstring("Unmarshal type error"))
+
+			resp = Serve(e, NewRequest("POST", "/lists/5/items", []byte(`[`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+
+			resp = Serve(e, NewRequest("POST", "/lists/5/items", []byte(`<items/>`), SetReqHeader("Content-Type", "application/xml")))
+			Expect(resp).To(HaveResponseCode(415))
+		})
+
+		It("panics if there are multiple body fields", func() {
+			type badParams struct {
+				A []int `body:"a"`
+				B []int `body:"b"`
+			}
+			Expect(func() { apiparams.ValidateStruct(&badParams{}) }).ToNot(Panic())
+			Expect(apiparams.ValidateStruct(&badParams{})).To(MatchError(ContainSubstring("multiple fields with a \"body\" tag")))
+		})
+	})
+
+	It("normalizes enum values to the casing of the choice", func() {
+		type handlerParams struct {
+			Animal string   `query:"animal" validate:"enum=bird|shark"`
+			Tags   []string `query:"tags" validate:"enum=Red|Blue"`
+		}
+		hp := handlerParams{}
+		group.GET("/foo", func(c echo.Context) error {
+			Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+			return c.NoContent(204)
+		})
+		Expect(Serve(e, GetRequest("/foo?animal=BIRD&tags=red&tags=BLUE"))).To(HaveResponseCode(204))
+		Expect(hp.Animal).To(Equal("bird"))
+		Expect(hp.Tags).To(Equal([]string{"Red", "Blue"}))
+	})
+
+	Describe("LenientJSONBools", func() {
+		type handlerParams struct {
+			Pretty  bool    `json:"pretty"`
+			Ptr     *bool   `json:"ptr"`
+			Flags   []bool  `json:"flags"`
+			ID      int     `json:"id"`
+			Comment *string `json:"comment"`
+		}
+
+		It("coerces bool strings to bools", func() {
+			hp := handlerParams{}
+			group.POST("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.LenientJSONBools())).To(Succeed())
+				return c.NoContent(204)
+			})
+			body := `{"pretty":"true","ptr":"0","flags":["1","false",true],"comment":"true"}`
+			Expect(Serve(e, NewRequest("POST", "/foo", []byte(body), JsonReq()))).To(HaveResponseCode(204))
+			Expect(hp.Pretty).To(BeTrue())
+			Expect(*hp.Ptr).To(BeFalse())
+			Expect(hp.Flags).To(Equal([]bool{true, false, true}))
+			Expect(*hp.Comment).To(Equal("true"))
+		})
+
+		It("400s for other strings", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}, apiparams.LenientJSONBools()))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"pretty":"yes"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+		})
+
+		It("does not coerce numbers unless LenientJSONNumbers is also used", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}, apiparams.LenientJSONBools()))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"pretty":"true","id":"1"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+
+			hp := handlerParams{}
+			group.PUT("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.LenientJSONBools(), apiparams.LenientJSONNumbers())).To(Succeed())
+				return c.NoContent(204)
+			})
+			resp = Serve(e, NewRequest("PUT", "/foo", []byte(`{"pretty":"true","id":"1"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(204))
+			Expect(hp.Pretty).To(BeTrue())
+			Expect(hp.ID).To(Equal(1))
+		})
+
+		It("is strict by default", func() {
+			group.POST("/foo", shouldFailHandler(&handlerParams{}))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"pretty":"true"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+		})
+	})
+
+	Describe("StrictJSON", func() {
+		type handlerParams struct {
+			ID int `json:"id"`
+		}
+		bindWith := func(opts ...interface{}) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				args := append([]interface{}{c}, opts...)
+				if err := apiparams.BindAndValidate(ad, &handlerParams{}, args...); err != nil {
+					return echo.NewHTTPError(err.Code(), err.Error())
+				}
+				return c.NoContent(200)
+			}
+		}
+
+		It("400s for unknown fields, naming the field", func() {
+			group.POST("/foo", bindWith(apiparams.StrictJSON()))
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"id":1,"bogus":1}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(400))
+			Expect(resp.Body.String()).To(ContainSubstring("bogus"))
+
+			resp = Serve(e, NewRequest("POST", "/foo", []byte(`{"id":1}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(200))
+		})
+
+		It("ignores unknown fields by default", func() {
+			group.POST("/foo", bindWith())
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"id":1,"bogus":1}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(200))
+		})
+	})
+
+	Describe("Provided", func() {
+		type handlerParams struct {
+			Count  int    `json:"count"`
+			Name   string `json:"name" default:"x"`
+			Page   int    `query:"page"`
+			Nested struct {
+				Content string `json:"content"`
+				Other   string `json:"other"`
+			} `json:"nested"`
+		}
+
+		It("reports which fields the client sent, even if they are zero values", func() {
+			var handler apiparams.Handler
+			group.POST("/foo", func(c echo.Context) error {
+				handler = apiparams.New(ad, &handlerParams{}, c)
+				Expect(handler.BindFromAll()).To(Succeed())
+				return c.NoContent(204)
+			})
+			body := `{"count":0,"nested":{"content":""},"other":null}`
+			Expect(Serve(e, NewRequest("POST", "/foo?page=0", []byte(body), JsonReq()))).To(HaveResponseCode(204))
+			Expect(handler.Provided("Count")).To(BeTrue())
+			Expect(handler.Provided("Page")).To(BeTrue())
+			Expect(handler.Provided("Nested")).To(BeTrue())
+			Expect(handler.Provided("Nested.Content")).To(BeTrue())
+			Expect(handler.Provided("Nested.Other")).To(BeFalse())
+			Expect(handler.Provided("Name")).To(BeFalse())
+		})
+
+		It("treats explicit nulls as not provided", func() {
+			var handler apiparams.Handler
+			group.POST("/foo", func(c echo.Context) error {
+				handler = apiparams.New(ad, &handlerParams{}, c)
+				Expect(handler.BindFromAll()).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, NewRequest("POST", "/foo", []byte(`{"count":null}`), JsonReq()))).To(HaveResponseCode(204))
+			Expect(handler.Provided("Count")).To(BeFalse())
+		})
+	})
+
+	Describe("embedded field name collisions", func() {
+		type baseParams struct {
+			Name string `query:"name"`
+			Page int    `query:"page" default:"1"`
+		}
+
+		It("uses the outer field if it is declared after the embedded struct", func() {
+			type listParams struct {
+				baseParams
+				Title string `query:"name"`
+			}
+			hp := listParams{}
+			group.GET("/things", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/things?name=x&page=2"))).To(HaveResponseCode(204))
+			Expect(hp.Title).To(Equal("x"))
+			Expect(hp.Name).To(BeEmpty())
+			Expect(hp.Page).To(Equal(2))
+		})
+
+		It("uses the outer field if it is declared before the embedded struct", func() {
+			type listParams struct {
+				Title string `query:"name"`
+				baseParams
+			}
+			hp := listParams{}
+			group.GET("/things", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/things?name=x"))).To(HaveResponseCode(204))
+			Expect(hp.Title).To(Equal("x"))
+			Expect(hp.Name).To(BeEmpty())
+			Expect(hp.Page).To(Equal(1))
+		})
+
+		It("panics if embedded fields at the same depth collide", func() {
+			type otherParams struct {
+				Label string `query:"name"`
+			}
+			type listParams struct {
+				baseParams
+				otherParams
+			}
+			hp := listParams{}
+			group.GET("/things", func(c echo.Context) error {
+				Expect(func() { apiparams.BindAndValidate(ad, &hp, c) }).To(
+					PanicWith(ContainSubstring(`multiple fields named "name"`)))
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/things"))).To(HaveResponseCode(204))
+		})
+	})
+
+	Describe("struct depth", func() {
+		It("panics for recursive parameter structs", func() {
+			type node struct {
+				Name     string `json:"name"`
+				Children []node `json:"children"`
+			}
+			Expect(func() { apiparams.New(StdlibAdapter{}, &node{}, nil, GetRequest("/")) }).To(
+				PanicWith(ContainSubstring("nested more than 32 levels deep")))
+		})
+
+		It("panics for structs deeper than MaxStructDepth", func() {
+			original := apiparams.MaxStructDepth
+			apiparams.MaxStructDepth = 1
+			DeferCleanup(func() { apiparams.MaxStructDepth = original })
+			type deepParams struct {
+				A struct {
+					B struct {
+						C string `json:"c"`
+					} `json:"b"`
+				} `json:"a"`
+			}
+			type shallowParams struct {
+				A struct {
+					B string `json:"b"`
+				} `json:"a"`
+			}
+			Expect(func() { apiparams.New(StdlibAdapter{}, &deepParams{}, nil, GetRequest("/")) }).To(
+				PanicWith(ContainSubstring("nested more than 1 levels deep")))
+			Expect(func() { apiparams.New(StdlibAdapter{}, &shallowParams{}, nil, GetRequest("/")) }).ToNot(Panic())
+		})
+	})
+
 	Describe("validation", func() {
 
 		type handlerParams struct {
@@ -644,6 +1227,25 @@ var _ = Describe("apiparams package", func() {
 			Expect(resp.Body.String()).To(ContainSubstring("s: invalid length"))
 		})
 
+		It("reports errors using the name of the source the field was bound from", func() {
+			type multiParams struct {
+				APIKey string `query:"api_key" header:"x-api-key" validate:"min=5"`
+			}
+			group.GET("/foo", shouldFailHandler(&multiParams{}))
+
+			resp := Serve(e, GetRequest("/foo", SetReqHeader("X-Api-Key", "abc")))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp.Body.String()).To(ContainSubstring("x-api-key: less than min"))
+
+			resp = Serve(e, GetRequest("/foo?api_key=abc"))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp.Body.String()).To(ContainSubstring("api_key: less than min"))
+
+			resp = Serve(e, GetRequest("/foo"))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp.Body.String()).To(ContainSubstring("api_key: less than min"))
+		})
+
 		It("422s for invalid query params", func() {
 			group.GET(
 				"/foo",
@@ -707,6 +1309,89 @@ var _ = Describe("apiparams package", func() {
 			Expect(resp.Body.String()).To(ContainSubstring(`nested.s: invalid length`))
 			Expect(resp.Body.String()).To(ContainSubstring(`slice[1].i: less than min`))
 		})
+
+		It("uses the validatemsg tag as the message, if present", func() {
+			type embedded struct {
+				E string `json:"e" validate:"len=2" validatemsg:"E must be 2 characters"`
+			}
+			type handlerParams struct {
+				embedded
+				Nested struct {
+					S string `json:"s" validate:"len=2" validatemsg:"S must be 2 characters"`
+				} `json:"nested"`
+				Slice []struct {
+					I int `json:"i" validate:"min=1" validatemsg:"I must be positive"`
+				} `json:"slice"`
+				Other string `json:"other" validate:"len=2"`
+			}
+			group.POST("/foo", func(c echo.Context) error {
+				err := apiparams.BindAndValidate(ad, &handlerParams{}, c)
+				return c.JSON(err.Code(), map[string]interface{}{"message": err.Error(), "errors": apiparams.FieldErrors(err)})
+			})
+			body := []byte(`{"e":"a","nested":{"s": "a"},"slice":[{"i":1},{"i":0}],"other":"a"}`)
+			resp := Serve(e, NewRequest("POST", "/foo", body, JsonReq()))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp.Body.String()).To(ContainSubstring(`nested.s: S must be 2 characters`))
+			Expect(resp.Body.String()).ToNot(ContainSubstring(`nested.s: invalid length`))
+			Expect(resp.Body.String()).To(ContainSubstring(`slice[1].i: I must be positive`))
+			Expect(resp.Body.String()).To(ContainSubstring(`e: E must be 2 characters`))
+			Expect(resp.Body.String()).To(ContainSubstring(`other: invalid length`))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("errors", ContainElement(
+				map[string]interface{}{"param": "nested.s", "validator": "len", "message": "S must be 2 characters"},
+			))))
+		})
+
+		It("translates messages with the TranslateMessages option", func() {
+			type handlerParams struct {
+				S  string `query:"s" validate:"len=2"`
+				ID string `query:"id" validate:"intid" validatemsg:"bad id"`
+			}
+			translations := map[string]map[string]string{
+				"fr": {"len": "longueur invalide"},
+			}
+			group.GET("/foo", func(c echo.Context) error {
+				locale := c.Request().Header.Get("Accept-Language")
+				translate := apiparams.TranslateMessages(func(param, validatorName, message string) string {
+					if t, ok := translations[locale][validatorName]; ok {
+						return t
+					}
+					return "[" + param + "] " + message
+				})
+				err := apiparams.BindAndValidate(ad, &handlerParams{}, c, translate)
+				return c.JSON(err.Code(), map[string]interface{}{"message": err.Error(), "errors": apiparams.FieldErrors(err)})
+			})
+			resp := Serve(e, GetRequest("/foo?s=a&id=x", SetReqHeader("Accept-Language", "fr")))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("message", SatisfyAll(
+				ContainSubstring("s: longueur invalide"),
+				ContainSubstring("id: [id] bad id"),
+			))))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("errors", ConsistOf(
+				map[string]interface{}{"param": "s", "validator": "len", "message": "longueur invalide"},
+				map[string]interface{}{"param": "id", "validator": "intid", "message": "[id] bad id"},
+			))))
+
+			resp = Serve(e, GetRequest("/foo?s=a&id=1"))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp).To(HaveJsonBody(HaveKeyWithValue("message", "s: [s] invalid length")))
+		})
+
+		It("includes the name of each failed validator in the field errors", func() {
+			type handlerParams struct {
+				S  string `json:"s" validate:"len=2"`
+				ID string `json:"id" validate:"intid"`
+			}
+			group.GET("/foo", func(c echo.Context) error {
+				err := apiparams.BindAndValidate(ad, &handlerParams{}, c)
+				return c.JSON(err.Code(), apiparams.FieldErrors(err))
+			})
+			resp := Serve(e, GetRequest("/foo?s=abc&id=x"))
+			Expect(resp).To(HaveResponseCode(422))
+			Expect(resp).To(HaveJsonBody(ConsistOf(
+				map[string]interface{}{"param": "s", "validator": "len", "message": "invalid length"},
+				map[string]interface{}{"param": "id", "validator": "intid", "message": "not an integer string"},
+			)))
+		})
 	})
 
 	It("passes the full feature test from the example", func() {
@@ -736,6 +1421,134 @@ var _ = Describe("apiparams package", func() {
 		Expect(resp).To(HaveResponseCode(200))
 	})
 
+	It("can validate against a fixed now", func() {
+		type handlerParams struct {
+			At time.Time `json:"at" validate:"comparenow=gt"`
+		}
+		frozen := func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+		group.POST("/foo", func(c echo.Context) error {
+			if err := apiparams.BindAndValidate(ad, &handlerParams{}, c, apiparams.WithNow(frozen)); err != nil {
+				return echo.NewHTTPError(err.Code(), err.Error())
+			}
+			return c.NoContent(204)
+		})
+		resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"at":"2020-01-01T00:00:01Z"}`), JsonReq()))
+		Expect(resp).To(HaveResponseCode(204))
+		resp = Serve(e, NewRequest("POST", "/foo", []byte(`{"at":"2020-01-01T00:00:00Z"}`), JsonReq()))
+		Expect(resp).To(HaveResponseCode(422))
+	})
+
+	It("defaults times to now", func() {
+		type handlerParams struct {
+			At    time.Time  `query:"at" default:"now"`
+			AtPtr *time.Time `query:"at_ptr" default:"now"`
+			Fixed time.Time  `query:"fixed" default:"2020-01-02T00:00:00Z"`
+		}
+		hp := handlerParams{}
+		group.GET("/foo", func(c echo.Context) error {
+			Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+			return c.NoContent(204)
+		})
+		Expect(Serve(e, GetRequest("/foo"))).To(HaveResponseCode(204))
+		Expect(hp.At).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(*hp.AtPtr).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(hp.Fixed).To(BeTemporally("==", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("binds durations", func() {
+		type handlerParams struct {
+			TTL     time.Duration  `query:"ttl"`
+			TTLPtr  *time.Duration `query:"ttl_ptr"`
+			Default time.Duration  `query:"default" default:"1m"`
+		}
+		hp := handlerParams{}
+		group.GET("/foo", func(c echo.Context) error {
+			if err := apiparams.BindAndValidate(ad, &hp, c); err != nil {
+				return echo.NewHTTPError(err.Code(), err.Error())
+			}
+			return c.NoContent(204)
+		})
+		Expect(Serve(e, GetRequest("/foo?ttl=500ms&ttl_ptr=30s"))).To(HaveResponseCode(204))
+		Expect(hp.TTL).To(Equal(500 * time.Millisecond))
+		Expect(hp.TTLPtr).ToNot(BeNil())
+		Expect(*hp.TTLPtr).To(Equal(30 * time.Second))
+		Expect(hp.Default).To(Equal(time.Minute))
+
+		Expect(Serve(e, GetRequest("/foo?ttl=banana"))).To(HaveResponseCode(400))
+	})
+
+	Describe("ValidateStruct", func() {
+		It("succeeds for supported field types", func() {
+			type params struct {
+				ID    int       `path:"id"`
+				Tags  []string  `query:"tags"`
+				Limit *int      `query:"limit" default:"10"`
+				At    time.Time `query:"at" default:"2020-01-01T00:00:00Z"`
+				Body  struct {
+					Content string `json:"content" default:"hi"`
+				} `json:"body"`
+				Meta map[string]string `json:"meta"`
+			}
+			Expect(apiparams.ValidateStruct(&params{})).To(Succeed())
+		})
+
+		It("errors for fields that cannot be bound", func() {
+			type status string
+			type params struct {
+				Status status            `query:"status"`
+				Meta   map[string]string `query:"meta"`
+				Kind   status            `json:"kind"`
+				Page   int               `json:"page" default:"abc"`
+			}
+			err := apiparams.ValidateStruct(&params{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(And(
+				ContainSubstring("field Status has type apiparams_test.status, which cannot be bound from query params"),
+				ContainSubstring("field Meta has type map[string]string, which cannot be bound from query params"),
+				ContainSubstring("field Kind has type apiparams_test.status, which cannot be bound from json params"),
+				ContainSubstring(`field Page has invalid default "abc"`),
+			))
+		})
+
+		It("errors for non-struct pointers", func() {
+			Expect(apiparams.ValidateStruct(5)).To(MatchError(ContainSubstring("expected a pointer to a struct")))
+		})
+	})
+
+	Describe("EchoValidator", func() {
+		type handlerParams struct {
+			Name string `json:"name" validate:"len=2"`
+			ID   string `json:"id" validate:"intid"`
+		}
+
+		BeforeEach(func() {
+			e.Validator = apiparams.EchoValidator{}
+		})
+
+		It("validates using the validator package, and maps field names", func() {
+			var validateErr error
+			group.POST("/foo", func(c echo.Context) error {
+				hp := handlerParams{}
+				Expect(c.Bind(&hp)).To(Succeed())
+				validateErr = c.Validate(&hp)
+				return c.NoContent(204)
+			})
+			Serve(e, NewRequest("POST", "/foo", []byte(`{"name":"abc","id":"5"}`), JsonReq()))
+			Expect(validateErr).To(MatchError("name: invalid length"))
+			httpErr, ok := validateErr.(apiparams.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.Code()).To(Equal(422))
+			Expect(apiparams.FieldErrors(httpErr)).To(ConsistOf(
+				apiparams.FieldError{Param: "name", Validator: "len", Message: "invalid length"},
+			))
+		})
+
+		It("returns nil if valid, and accepts non-pointer structs", func() {
+			Expect(apiparams.EchoValidator{}.Validate(handlerParams{Name: "ab", ID: "1"})).To(Succeed())
+			Expect(apiparams.EchoValidator{}.Validate(handlerParams{Name: "ab", ID: "x"})).To(MatchError("id: not an integer string"))
+		})
+	})
+
 	Describe("StdlibAdapter", func() {
 		It("can be used for success", func() {
 			type noteParams struct {
@@ -835,6 +1648,176 @@ var _ = Describe("apiparams package", func() {
 			},
 		})
 
+		type Celsius float64
+		type Fahrenheit float64
+
+		apiparams.RegisterCustomTypes(
+			apiparams.CustomTypeDef{
+				Value: Celsius(0),
+				Parser: func(v string, usePtr bool) (reflect.Value, error) {
+					f, err := strconv.ParseFloat(v, 64)
+					c := Celsius(f)
+					if usePtr {
+						return reflect.ValueOf(&c), err
+					}
+					return reflect.ValueOf(c), err
+				},
+			},
+			apiparams.CustomTypeDef{
+				Value: Fahrenheit(0),
+				Parser: func(v string, usePtr bool) (reflect.Value, error) {
+					f, err := strconv.ParseFloat(v, 64)
+					c := Fahrenheit(f)
+					if usePtr {
+						return reflect.ValueOf(&c), err
+					}
+					return reflect.ValueOf(c), err
+				},
+			},
+		)
+
+		It("can register several types at once", func() {
+			type handlerParams struct {
+				C Celsius    `query:"c"`
+				F Fahrenheit `query:"f"`
+			}
+			group.GET(
+				"/foo",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					Expect(hp.C).To(Equal(Celsius(10)))
+					Expect(hp.F).To(Equal(Fahrenheit(50)))
+					return c.JSON(http.StatusOK, 1)
+				},
+			)
+			Expect(Serve(e, GetRequest("/foo?c=10&f=50"))).To(HaveResponseCode(200))
+		})
+
+		It("binds slices of custom types", func() {
+			type handlerParams struct {
+				Names []MyString  `query:"name"`
+				Temps *[]Celsius  `query:"temp"`
+				Times []*UnixTime `query:"t"`
+				Big   []int64     `query:"big"`
+			}
+			Expect(apiparams.ValidateStruct(&handlerParams{})).To(Succeed())
+			group.GET(
+				"/foo",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					Expect(hp.Names).To(Equal([]MyString{"a", "b"}))
+					Expect(hp.Temps).To(Equal(&[]Celsius{10, 20.5}))
+					Expect(hp.Times).To(HaveLen(1))
+					Expect(time.Time(*hp.Times[0]).Unix()).To(BeEquivalentTo(100))
+					Expect(hp.Big).To(Equal([]int64{1, 2}))
+					return c.JSON(http.StatusOK, 1)
+				},
+			)
+			Expect(Serve(e, GetRequest("/foo?name=a&name=b&temp=10&temp=20.5&t=100&big=1&big=2"))).To(HaveResponseCode(200))
+		})
+
+		It("400s if an element of a custom type slice cannot be parsed", func() {
+			type handlerParams struct {
+				Temps []Celsius `query:"temp"`
+			}
+			group.GET("/foo", shouldFailHandler(&handlerParams{}))
+			Expect(Serve(e, GetRequest("/foo?temp=10&temp=hot"))).To(HaveResponseCode(400))
+		})
+
+		It("panics if a type is registered twice", func() {
+			Expect(func() {
+				apiparams.RegisterCustomType(apiparams.CustomTypeDef{Value: MyString("")})
+			}).To(PanicWith(ContainSubstring("is already registered")))
+		})
+
+		It("can register a type for a single handler, which takes precedence over a global type", func() {
+			upper := apiparams.WithCustomType(apiparams.CustomTypeDef{
+				Value: MyString(""),
+				Parser: func(v string, usePtr bool) (reflect.Value, error) {
+					s := MyString(strings.ToUpper(v))
+					if usePtr {
+						return reflect.ValueOf(&s), nil
+					}
+					return reflect.ValueOf(s), nil
+				},
+			})
+			type handlerParams struct {
+				S MyString `query:"s"`
+			}
+			var local, global handlerParams
+			group.GET("/local", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &local, c, upper)).To(Succeed())
+				return c.NoContent(204)
+			})
+			group.GET("/global", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &global, c)).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/local?s=abc"))).To(HaveResponseCode(204))
+			Expect(Serve(e, GetRequest("/global?s=abc"))).To(HaveResponseCode(204))
+			Expect(local.S).To(Equal(MyString("ABC")))
+			Expect(global.S).To(Equal(MyString("abc")))
+		})
+
+		It("can register a type for a single handler that is not registered globally", func() {
+			type Local string
+			type handlerParams struct {
+				L *Local `query:"l"`
+			}
+			hp := handlerParams{}
+			group.GET("/foo", func(c echo.Context) error {
+				Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.WithCustomType(apiparams.CustomTypeDef{
+					Value: Local(""),
+					Parser: func(v string, usePtr bool) (reflect.Value, error) {
+						s := Local("local-" + v)
+						return reflect.ValueOf(&s), nil
+					},
+				}))).To(Succeed())
+				return c.NoContent(204)
+			})
+			Expect(Serve(e, GetRequest("/foo?l=x"))).To(HaveResponseCode(204))
+			Expect(*hp.L).To(Equal(Local("local-x")))
+		})
+
+		It("can override and deregister a type", func() {
+			type Overridden string
+			parser := func(prefix string) apiparams.Parser {
+				return func(v string, usePtr bool) (reflect.Value, error) {
+					s := Overridden(prefix + v)
+					if usePtr {
+						return reflect.ValueOf(&s), nil
+					}
+					return reflect.ValueOf(s), nil
+				}
+			}
+			apiparams.RegisterCustomType(apiparams.CustomTypeDef{Value: Overridden(""), Parser: parser("first-")})
+			DeferCleanup(func() {
+				apiparams.DeregisterCustomType(Overridden(""))
+			})
+			apiparams.OverrideCustomType(apiparams.CustomTypeDef{Value: Overridden(""), Parser: parser("second-")})
+
+			type handlerParams struct {
+				O Overridden `query:"o"`
+			}
+			group.GET(
+				"/foo",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					Expect(hp.O).To(Equal(Overridden("second-x")))
+					return c.JSON(http.StatusOK, 1)
+				},
+			)
+			Expect(Serve(e, GetRequest("/foo?o=x"))).To(HaveResponseCode(200))
+
+			apiparams.DeregisterCustomType(Overridden(""))
+			Expect(func() {
+				Serve(e, GetRequest("/foo?o=x"))
+			}).To(Panic())
+		})
+
 		It("can get defaults", func() {
 			type handlerParams struct {
 				UnixTime          UnixTime    `default:"20"`
@@ -899,6 +1882,83 @@ var _ = Describe("apiparams package", func() {
 			Field string `json:"field"`
 		}
 
+		It("leaves the body readable by the handler", func() {
+			group.POST(
+				"/foo",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					Expect(hp.Field).To(Equal("1"))
+					body, err := io.ReadAll(c.Request().Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal(`{"field":"1"}`))
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					return c.NoContent(204)
+				},
+			)
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"field":"1"}`), JsonReq(), func(r *http.Request) {
+				r.GetBody = nil
+			}))
+			Expect(resp).To(HaveResponseCode(204))
+		})
+
+		It("leaves the body readable when the request has GetBody", func() {
+			group.POST(
+				"/foo",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c)).To(Succeed())
+					Expect(hp.Field).To(Equal("1"))
+					body, err := io.ReadAll(c.Request().Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal(`{"field":"1"}`))
+					return c.NoContent(204)
+				},
+			)
+			resp := Serve(e, NewRequest("POST", "/foo", []byte(`{"field":"1"}`), JsonReq()))
+			Expect(resp).To(HaveResponseCode(204))
+		})
+
+		It("consumes the body with the ConsumeBody option", func() {
+			type xmlParams struct {
+				Field string `xml:"field"`
+			}
+			apiparams.RegisterBodyDecoder("application/xml", func(body io.Reader, ptr interface{}) error {
+				return xml.NewDecoder(body).Decode(ptr)
+			})
+			DeferCleanup(apiparams.DeregisterBodyDecoder, "application/xml")
+			group.POST(
+				"/json",
+				func(c echo.Context) error {
+					hp := handlerParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.ConsumeBody())).To(Succeed())
+					Expect(hp.Field).To(Equal("1"))
+					Expect(c.Request().GetBody).To(BeNil())
+					body, err := io.ReadAll(c.Request().Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(body).To(BeEmpty())
+					return c.NoContent(204)
+				},
+			)
+			group.POST(
+				"/xml",
+				func(c echo.Context) error {
+					hp := xmlParams{}
+					Expect(apiparams.BindAndValidate(ad, &hp, c, apiparams.ConsumeBody())).To(Succeed())
+					Expect(hp.Field).To(Equal("2"))
+					Expect(c.Request().GetBody).To(BeNil())
+					return c.NoContent(204)
+				},
+			)
+			noGetBody := func(r *http.Request) {
+				r.GetBody = nil
+			}
+			resp := Serve(e, NewRequest("POST", "/json", []byte(`{"field":"1"}`), JsonReq(), noGetBody))
+			Expect(resp).To(HaveResponseCode(204))
+			resp = Serve(e, NewRequest("POST", "/xml", []byte(`<x><field>2</field></x>`), SetReqHeader("Content-Type", "application/xml"), noGetBody))
+			Expect(resp).To(HaveResponseCode(204))
+		})
+
 		It("succeeds (older versions of Go would fail)", func() {
 			type handlerParams2 struct {
 				Field string `json:"field2"`
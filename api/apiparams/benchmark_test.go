@@ -58,6 +58,7 @@ func BenchmarkAPIParamsJSONBind(b *testing.B) {
 	if err != nil {
 		panic(err.Error())
 	}
+	req.Header.Set("Content-Type", "application/json")
 	adapter := NullAdapter{request: req}
 
 	b.ResetTimer()
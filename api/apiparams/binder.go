@@ -1,7 +1,9 @@
 package apiparams
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,15 +17,26 @@ type binder struct {
 	req                              *http.Request
 	routeParamKeys, routeParamValues []string
 	typeDefaulters                   map[reflect.Type]Defaulter
+	opts                             handlerOptions
+	// boundParamNames maps struct field names to the parameter name that last set them
+	// (from headers, query, path, or form params).
+	// Used to report errors using the name the client sent.
+	boundParamNames map[string]string
+	// provided tracks the struct fields the client sent a value for, from any source.
+	// Fields set only from defaults are not included.
+	provided *presence
 }
 
-func newBinder(r reflector, req *http.Request, routeParamKeys, routeParamValues []string) binder {
+func newBinder(r reflector, req *http.Request, routeParamKeys, routeParamValues []string, opts handlerOptions) binder {
 	b := binder{
 		r,
 		req,
 		routeParamKeys,
 		routeParamValues,
 		make(map[reflect.Type]Defaulter),
+		opts,
+		make(map[string]string),
+		newPresence(),
 	}
 	return b
 }
@@ -33,33 +46,46 @@ func (b binder) RegisterDefaulter(t reflect.Type, d Defaulter) {
 }
 
 // BindFromAll fills in the struct instance from defaults, the JSON body, query params, and path params.
+// Structs with only json fields and no defaults (see isJSONOnly) skip setting defaults,
+// and requests without a query string skip query params,
+// so binding a plain JSON body is close to the speed of json.Unmarshal.
+// Headers, path params, and forms are still bound,
+// since json fields can be set from any source.
 func (b binder) BindFromAll() HTTPError {
-	if err := b.setFromDefaults(b.reflector.Underlying()); err != nil {
-		return err
+	if !b.reflector.jsonOnly {
+		if err := b.setFromDefaults(b.reflector.Underlying()); err != nil {
+			return err
+		}
 	}
 	if err := b.setFromHeaders(); err != nil {
 		return err
 	}
-	if err := b.setFromJSONBody(); err != nil {
+	if err := b.setFromBody(); err != nil {
 		return err
 	}
 	if err := b.setFromForm(); err != nil {
 		return err
 	}
-	if err := b.setFromQueryParams(); err != nil {
+	if err := b.setFromFiles(); err != nil {
 		return err
 	}
+	if b.req.URL.RawQuery != "" {
+		if err := b.setFromQueryParams(); err != nil {
+			return err
+		}
+	}
 	if err := b.setFromPathParams(); err != nil {
 		return err
 	}
 	return nil
 }
 
-// Marshal the body into JSON, bound to the parameter struct.
-// Return an error if the content-type is not JSON,
+// Decode the body into the parameter struct,
+// using the body decoder registered for the content-type (usually JSON).
+// Return an error if there is no decoder for the content-type,
 // or any other error occurs (bad unmarshaling).
 // Noop if there is no body.
-func (b binder) setFromJSONBody() HTTPError {
+func (b binder) setFromBody() HTTPError {
 	if b.req.ContentLength == 0 {
 		return nil
 	}
@@ -68,23 +94,70 @@ func (b binder) setFromJSONBody() HTTPError {
 		return NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to parse form: %s", err.Error()))
 	}
 	ctype := b.req.Header.Get("Content-Type")
-	switch {
-	case strings.HasPrefix(ctype, "application/json"):
-		body, err := b.requestBody()
+	if ctype == "application/x-www-form-urlencoded" {
+		// Handled by ParseForm.
+		return nil
+	}
+	if strings.HasPrefix(ctype, "multipart/form-data") {
+		// Values are added to the form, like with ParseForm.
+		return b.parseMultipartForm()
+	}
+	def, ok := findBodyDecoder(ctype)
+	if !ok {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "")
+	}
+	if def.contentType != mimeApplicationJSON {
+		body, err := b.requestBodyReader()
 		if err != nil {
 			return NewHTTPError(http.StatusBadRequest, err.Error())
 		}
-		return b.decodeJSON(body)
-	case ctype == "application/x-www-form-urlencoded":
-		// Handled by ParseForm.
+		return bodyDecodeError(def.decoder(body, b.reflector.BodyPointer()))
+	}
+	// JSON bodies are always read into memory, since we need the keys to track presence.
+	buf, err := b.requestBody()
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var body io.Reader = bytes.NewReader(buf)
+	if b.reflector.bodyField == nil {
+		b.provided.body, b.provided.bodyType = buf, b.reflector.Underlying().Type()
+	} else if !bytes.Equal(bytes.TrimSpace(buf), []byte("null")) {
+		b.provided.fields[b.reflector.Underlying().Type().FieldByIndex(b.reflector.bodyField).Name] = true
+	}
+	coercion := jsonCoercion{numbers: b.opts.lenientJSONNumbers, bools: b.opts.lenientJSONBools}
+	if coercion.numbers || coercion.bools {
+		coerced, err := coerceJSON(body, b.reflector.BodyType(), coercion)
+		if err != nil {
+			return bodyDecodeError(err)
+		}
+		body = coerced
+	}
+	if b.opts.strictJSON {
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		return bodyDecodeError(dec.Decode(b.reflector.BodyPointer()))
+	}
+	return bodyDecodeError(def.decoder(body, b.reflector.BodyPointer()))
+}
+
+// parseMultipartForm parses a multipart body, holding at most
+// the configured max memory in RAM.
+// Return a 413 if the body is larger than a limit set with http.MaxBytesReader.
+func (b binder) parseMultipartForm() HTTPError {
+	err := b.req.ParseMultipartForm(b.opts.multipartMaxMemory)
+	if err == nil {
 		return nil
-	default:
-		return NewHTTPError(http.StatusUnsupportedMediaType, "")
 	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "")
+	}
+	return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse multipart form: %s", err.Error()))
 }
 
-func (b binder) decodeJSON(body io.Reader) HTTPError {
-	if err := json.NewDecoder(body).Decode(b.reflector.Pointer()); err == nil {
+// bodyDecodeError converts an error from decoding a body into an HTTPError (or nil if err is nil).
+func bodyDecodeError(err error) HTTPError {
+	if err == nil {
 		return nil
 	} else if ute, ok := err.(*json.UnmarshalTypeError); ok {
 		return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, offset=%v", ute.Type, ute.Value, ute.Offset))
@@ -95,11 +168,47 @@ func (b binder) decodeJSON(body io.Reader) HTTPError {
 	}
 }
 
-func (b binder) requestBody() (io.Reader, error) {
-	if b.req.GetBody == nil {
+// requestBody reads the whole request body into memory.
+// If the request has GetBody, the body is read from it, and the request's Body is left alone.
+// Otherwise, the body is read from the request's Body,
+// and Body and GetBody are replaced so that the body can be read again
+// (by later binds, or by the handler itself, like to verify a signature),
+// unless the ConsumeBody option is used.
+func (b binder) requestBody() ([]byte, error) {
+	if b.req.GetBody != nil {
+		body, err := b.req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	buf, err := io.ReadAll(b.req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = b.req.Body.Close()
+	if !b.opts.consumeBody {
+		b.req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf)), nil
+		}
+		b.req.Body, _ = b.req.GetBody()
+	}
+	return buf, nil
+}
+
+// requestBodyReader returns a reader for the request body, for decoders that can stream it.
+// With ConsumeBody, the request's Body is read directly, without buffering;
+// otherwise this is the same as requestBody.
+func (b binder) requestBodyReader() (io.Reader, error) {
+	if b.opts.consumeBody && b.req.GetBody == nil {
 		return b.req.Body, nil
 	}
-	return b.req.GetBody()
+	buf, err := b.requestBody()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
 }
 
 // Walk over the form body, if any, and apply values.
@@ -109,6 +218,7 @@ func (b binder) setFromForm() HTTPError {
 		return nil
 	}
 	for k, values := range b.req.Form {
+		k = trimArraySuffix(k)
 		for _, v := range values {
 			if err := b.setField(k, v, ParamSourceForm); err != nil {
 				return err
@@ -118,6 +228,33 @@ func (b binder) setFromForm() HTTPError {
 	return nil
 }
 
+// Set *multipart.FileHeader and []*multipart.FileHeader fields
+// from the files in a multipart form body, if any.
+// A *multipart.FileHeader field is set to the first file with its name.
+func (b binder) setFromFiles() HTTPError {
+	if b.req.MultipartForm == nil {
+		return nil
+	}
+	for k, files := range b.req.MultipartForm.File {
+		fieldDef, ok := b.reflector.ParamFieldFor(trimArraySuffix(k))
+		if !ok || !fieldDef.CanSetFrom(ParamSourceFile) || len(files) == 0 {
+			continue
+		}
+		field := b.reflector.FieldFor(fieldDef.StructField)
+		switch fieldDef.StructField.Type {
+		case typeOfFileHeader:
+			field.Set(reflect.ValueOf(files[0]))
+		case typeOfFileHeaderSlice:
+			field.Set(reflect.AppendSlice(field, reflect.ValueOf(files)))
+		default:
+			continue
+		}
+		b.boundParamNames[fieldDef.StructField.Name] = fieldDef.Name
+		b.provided.fields[fieldDef.StructField.Name] = true
+	}
+	return nil
+}
+
 // Walk over all the fields of a struct,
 // setting fields according to any "default" struct tags.
 // This function is called recursively if the field of a struct
@@ -139,7 +276,7 @@ func (b binder) setFromDefaults(st reflect.Value) HTTPError {
 		if defaultValue == "" {
 			continue
 		}
-		if defaulter := b.typeDefaulters[fieldDef.Type]; defaulter != nil {
+		if defaulter := b.typeDefaulters[derefType(fieldDef.Type)]; defaulter != nil {
 			defaultValue = defaulter(defaultValue)
 		}
 
@@ -167,13 +304,7 @@ func (b binder) setFromHeaders() HTTPError {
 // Set struct fields from the URL query parameters.
 func (b binder) setFromQueryParams() HTTPError {
 	for k, values := range b.req.URL.Query() {
-		key := k
-		// Convention for array query params is key[]=val1&key[]=val2, which will be key: {val1, val2}
-		// when parsed by Go. Remove the trailing []. We do this safely, if anyone is actually depending on
-		// "[]" as part of a meaningful JSON key, they probably have a use case outside of apiparams.
-		if strings.HasSuffix(key, "[]") {
-			key = strings.TrimSuffix(key, "[]")
-		}
+		key := trimArraySuffix(k)
 		for _, v := range values {
 			if err := b.setField(key, v, ParamSourceQuery); err != nil {
 				return err
@@ -183,6 +314,13 @@ func (b binder) setFromQueryParams() HTTPError {
 	return nil
 }
 
+// Convention for array query and form params is key[]=val1&key[]=val2, which will be key: {val1, val2}
+// when parsed by Go. Remove the trailing []. We do this safely, if anyone is actually depending on
+// "[]" as part of a meaningful JSON key, they probably have a use case outside of apiparams.
+func trimArraySuffix(key string) string {
+	return strings.TrimSuffix(key, "[]")
+}
+
 // Set struct fields from route/path param values.
 func (b binder) setFromPathParams() HTTPError {
 	for i, name := range b.routeParamKeys {
@@ -206,9 +344,19 @@ func (b binder) setField(paramName, paramValue string, source ParamSource) HTTPE
 		// This is unavoidable ("?_=123456"), so no issue.
 		return nil
 	}
+	if isFileType(fieldDef.StructField.Type) {
+		// Only set from uploaded files, in setFromFiles.
+		return nil
+	}
+	if paramValue == "" && b.opts.emptyAsUnset && fieldDef.StructField.Type.Kind() == reflect.Ptr &&
+		(source == ParamSourceQuery || source == ParamSourceForm) {
+		return nil
+	}
 	field := b.reflector.FieldFor(fieldDef.StructField)
 	if err := b.reflector.setField(fieldDef.StructField, field, paramValue); err != nil {
 		return NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	b.boundParamNames[fieldDef.StructField.Name] = fieldDef.Name
+	b.provided.fields[fieldDef.StructField.Name] = true
 	return nil
 }
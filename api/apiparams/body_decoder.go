@@ -0,0 +1,70 @@
+package apiparams
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BodyDecoder decodes a request body into the parameter struct pointer,
+// like json.Unmarshal or xml.Unmarshal.
+// A non-nil error results in a 400.
+type BodyDecoder func(body io.Reader, paramsStructPtr interface{}) error
+
+type bodyDecoderDef struct {
+	contentType string
+	decoder     BodyDecoder
+}
+
+var bodyDecoders = make([]bodyDecoderDef, 0, 2)
+
+// RegisterBodyDecoder registers a decoder for request bodies with the given Content-Type,
+// like "application/xml". A request's Content-Type matches if it starts with contentType,
+// so parameters like "; charset=utf-8" are allowed. If more than one decoder matches,
+// the one with the longest contentType is used.
+//
+// A decoder for "application/json" is registered by default.
+// Requests with a body that no decoder matches (other than form bodies) are rejected with a 415.
+//
+// Panic if a decoder for the same Content-Type is already registered,
+// since it is a programming error.
+func RegisterBodyDecoder(contentType string, decoder BodyDecoder) {
+	for _, existing := range bodyDecoders {
+		if existing.contentType == contentType {
+			panic(fmt.Sprintf("apiparams: body decoder for %s is already registered", contentType))
+		}
+	}
+	bodyDecoders = append(bodyDecoders, bodyDecoderDef{contentType, decoder})
+}
+
+// DeregisterBodyDecoder removes the decoder for the Content-Type, if one is registered.
+func DeregisterBodyDecoder(contentType string) {
+	for i, existing := range bodyDecoders {
+		if existing.contentType == contentType {
+			bodyDecoders = append(bodyDecoders[:i:i], bodyDecoders[i+1:]...)
+			return
+		}
+	}
+}
+
+// findBodyDecoder returns the registered decoder whose Content-Type best matches ctype.
+func findBodyDecoder(ctype string) (bodyDecoderDef, bool) {
+	var best bodyDecoderDef
+	found := false
+	for _, def := range bodyDecoders {
+		if strings.HasPrefix(ctype, def.contentType) && len(def.contentType) > len(best.contentType) {
+			best = def
+			found = true
+		}
+	}
+	return best, found
+}
+
+func init() {
+	RegisterBodyDecoder(mimeApplicationJSON, func(body io.Reader, paramsStructPtr interface{}) error {
+		return json.NewDecoder(body).Decode(paramsStructPtr)
+	})
+}
+
+const mimeApplicationJSON = "application/json"
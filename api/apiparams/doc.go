@@ -37,12 +37,15 @@ Note all the benefits:
   - Data is pulled from path parameters, query parameters, any JSON body,
     and defaults defined in struct tags. The variable names used for values
     is specified via the appropriate struct tag.
-    See ParamSource for more details, but possible tags are "path", "query", "header", "form", and "json".
+    See ParamSource for more details, but possible tags are "path", "query", "header", "form", "file", and "json".
     The "json" tag will bind from any source, not just a JSON request body.
+    A field can have tags for multiple sources, like `query:"api_key" header:"x-api-key"`;
+    validation errors use the name from the source the field was bound from.
     This makes it clear at the endpoint and model definitions where data comes from and
     how an endpoint is supposed to be called.
   - Path and query param coercion is done from the basic JSON types,
-    depending on the struct field type (int/float, string, bool).
+    depending on the struct field type (int/uint/float, string, bool).
+    Slices of these (and of custom types) are bound from repeated params, like "?id=1&id=2".
   - Validation is done using the validator package.
     Custom validators can be registered as we need to express more
     sophisticated validations.
@@ -51,6 +54,9 @@ Note all the benefits:
 
 See validator for a list of available validators and usage examples.
 
+To use the same validation and error formatting with echo's own binding,
+set e.Validator = apiparams.EchoValidator{} and call c.Validate.
+
 # Adapters
 
 The only non-obvious prerequisite to using apiparams.BindAndValidate is
@@ -129,13 +135,100 @@ Note again that in general only one of these need to be defined and once per-pro
 
 apiparams.BindAndValidate returns a apiparams.HTTPError. Nil result means no error.
 The HTTPError can be one of various error codes (415, 422, 400, 500)
-for reasons like an incorrect Content-Type (a body with any type but "application/json"),
+for reasons like an incorrect Content-Type (a body with a type that has no body decoder),
 unparseable value (like "abc" for an integer field),
 parseable-but-invalid value (like a too-high number), or malformed JSON.
 
 Callers should wrap the result in the appropriate error for their framework,
 or can write the Code and Message to the HTTP response.
 
+For validation errors, FieldErrors returns the parameter, message,
+and name of the failed validator (like "len") for each error,
+so clients can handle errors programmatically or localize messages.
+
+To replace the message of a field's validation errors with something more readable,
+use the validatemsg struct tag, like `json:"name" validate:"len=2" validatemsg:"Name must be 2 characters"`.
+To translate messages for a request, like based on its Accept-Language header,
+use the TranslateMessages option, which is called with the parameter, validator name,
+and message of each error.
+
+# Pointers
+
+Pointer fields are left nil if no value is provided, so they can be used for optional parameters.
+Note that an empty query or form value, like "?s=", binds a *string to a pointer to "".
+To treat empty values as unset for pointer fields instead, use the EmptyAsUnset option.
+
+# Presence
+
+Since a zero value cannot be told apart from a missing value,
+Handler.Provided reports whether the client sent a value for a field,
+from any source. For JSON bodies, this is based on the keys present in the body,
+so {"count":0} provides Count, but {} and {"count":null} do not.
+
+# Body Fields
+
+By default, the request body is decoded into the parameter struct,
+so it must be a JSON object. To accept another kind of body, like a top-level JSON array
+for a batch endpoint, tag a top-level field with "body".
+The whole body is decoded into that field, and other fields bind from path and query params as usual:
+
+	type createItemsParams struct {
+		ListID int    `path:"id"`
+		Items  []Item `body:"items" validate:"min=1"`
+	}
+
+The tag value is the name used for the field in validation errors, like "items[0].name".
+
+# Body Decoders
+
+Request bodies are decoded using the decoder registered for their Content-Type.
+Only "application/json" is registered by default (form bodies are bound like query params).
+Fields in a JSON body that do not match the parameter struct are ignored;
+use the StrictJSON option to reject them with a 400 instead.
+
+After binding, the request body can be read again, like to verify a signature,
+since apiparams keeps it in memory and replaces the request's Body and GetBody.
+Use the ConsumeBody option if nothing else needs to read the body.
+
+To accept other types, like XML, register a decoder:
+
+	apiparams.RegisterBodyDecoder("application/xml", func(body io.Reader, ptr interface{}) error {
+		return xml.NewDecoder(body).Decode(ptr)
+	})
+
+# Options
+
+Options can be passed along with the handler arguments to customize binding for a single call:
+
+	apiparams.BindAndValidate(adapter, &params, c, apiparams.LenientJSONNumbers())
+
+Options are removed from the handler arguments before they are passed to the Adapter.
+See the functions returning Option for what is available.
+
+By default, JSON body values must match the types of their fields exactly.
+Use LenientJSONNumbers to accept numeric strings for number fields (and numbers for string fields),
+and LenientJSONBools to accept "true", "false", "1", and "0" strings for bool fields.
+
+# Multipart Forms
+
+multipart/form-data bodies are bound like other form bodies.
+At most DefaultMultipartMaxMemory bytes are held in memory while parsing
+(use the MultipartMaxMemory option to change this), and the rest is stored on disk.
+This is separate from the limit on the size of the body, which apiparams does not enforce:
+use something like echo's BodyLimit middleware, which should be at least the max memory.
+If the body is over a limit set with http.MaxBytesReader, the error is a 413.
+
+Uploaded files are bound to *multipart.FileHeader fields (the first file with the name)
+or []*multipart.FileHeader fields (all files with the name) with a "file" tag:
+
+	type uploadParams struct {
+		Title  string                `form:"title"`
+		Avatar *multipart.FileHeader `file:"avatar" validate:"nonzero"`
+	}
+
+Like query params, repeated form fields (tags=a&tags=b or tags[]=a&tags[]=b)
+populate slice fields.
+
 # Custom Types
 
 Custom types can be used in an API by providing a CustomTypeDef and passing it to RegisterCustomType.
@@ -143,6 +236,9 @@ A CustomTypeDef consists of a _defaulter_ and a _parser_.
 
 Note that a custom type is automatically registered for time.Time,
 as shown in this documentation.
+A custom type is also registered for time.Duration, parsed with time.ParseDuration,
+so values like "?ttl=30s" and `default:"1m"` work.
+Note that JSON bodies still decode a time.Duration as an integer number of nanoseconds.
 
 The _parser_ takes a string and returns a reflect.Value that can be used to set a field
 of the custom type.
@@ -192,6 +288,32 @@ tag value of "now":
 	})
 
 Note also the defaulting behavior for a Time demonstrated in previous sections.
+The built-in time.Time definition has a defaulter like this,
+so `default:"now"` works for time.Time and *time.Time fields;
+other default values are parsed as RFC3339 times.
+
+Only one definition can be registered for a type;
+calling RegisterCustomType for a type that is already registered panics.
+To replace a definition, including the built-in time.Time definition,
+use OverrideCustomType. For example, to parse times as dates:
+
+	apiparams.OverrideCustomType(apiparams.CustomTypeDef{
+		Value: time.Time{},
+		Parser: func(value string, usePtr bool) (reflect.Value, error) {
+			v, err := time.Parse("2006-01-02", value)
+			if usePtr {
+				return reflect.ValueOf(&v), err
+			}
+			return reflect.ValueOf(v), err
+		},
+	})
+
+Use DeregisterCustomType to remove a definition entirely.
+
+To use a definition for only one Handler, pass it as an option with WithCustomType.
+It takes precedence over a global definition for the same type:
+
+	apiparams.BindAndValidate(adapter, &params, c, apiparams.WithCustomType(def))
 
 The custom defaulter methods may want to panic if the value is invalid-
 the value is read from the struct tags, so is known at compile time and will never change.
@@ -0,0 +1,36 @@
+package apiparams
+
+import (
+	"github.com/lithictech/go-aperitif/v2/validator"
+	"net/http"
+	"reflect"
+)
+
+// EchoValidator implements echo's Validator interface using the validator package,
+// so code using echo's binding can use our validators and error formatting
+// by setting e.Validator = apiparams.EchoValidator{} and calling c.Validate.
+// It does not import echo, so apiparams stays framework-agnostic.
+//
+// Validation errors are returned as an HTTPError like those from BindAndValidate
+// (422, with field names mapped to parameter names, and FieldErrors),
+// so the api package error handling converts them to an api.Error.
+type EchoValidator struct{}
+
+// Validate validates i, which should be a struct or pointer to a struct.
+func (EchoValidator) Validate(i interface{}) error {
+	err := validator.Validate(i)
+	if err == nil {
+		return nil
+	}
+	// The reflector, used to map field names, needs a pointer to a struct.
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Struct {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	return validationError(newReflector(v.Interface()), err, nil, nil)
+}
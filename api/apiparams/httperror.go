@@ -17,8 +17,9 @@ type HTTPError interface {
 }
 
 type httpError struct {
-	code     int
-	messages []string
+	code        int
+	messages    []string
+	fieldErrors []FieldError
 }
 
 func (e httpError) Code() int {
@@ -37,5 +38,28 @@ func NewHTTPError(code int, message string) HTTPError {
 	if message == "" {
 		message = http.StatusText(code)
 	}
-	return httpError{code, []string{message}}
+	return httpError{code, []string{message}, nil}
+}
+
+// FieldError describes a single validation failure for a parameter.
+// It is serializable so it can be included in an error response.
+type FieldError struct {
+	// Param is the name of the parameter, as it is used in Messages.
+	Param string `json:"param"`
+	// Validator is the name of the validator that failed, like "len" or "intid".
+	// Clients can use this to localize messages or handle errors programmatically.
+	// It is empty if the validator is not known.
+	Validator string `json:"validator"`
+	// Message is the validation error message, like "invalid length".
+	Message string `json:"message"`
+}
+
+// FieldErrors returns the per-field validation errors for err,
+// in the same order as err.Messages().
+// Return nil if err is not from a failed validation.
+func FieldErrors(err HTTPError) []FieldError {
+	if he, ok := err.(httpError); ok {
+		return he.fieldErrors
+	}
+	return nil
 }
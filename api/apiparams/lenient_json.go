@@ -0,0 +1,164 @@
+package apiparams
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var (
+	typeOfJSONUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	typeOfTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// jsonCoercion is which lenient conversions coerceJSON does.
+type jsonCoercion struct {
+	// numbers converts numeric strings to numbers (and numbers to strings).
+	// See LenientJSONNumbers.
+	numbers bool
+	// bools converts "true", "false", "1", and "0" strings to booleans.
+	// See LenientJSONBools.
+	bools bool
+}
+
+// coerceJSON decodes the JSON in body,
+// converts values to match the types of the corresponding fields of t as configured by c,
+// and returns the re-encoded JSON.
+// Values that cannot be coerced are left alone,
+// so they fail with the usual error when decoding into the parameter struct.
+func coerceJSON(body io.Reader, t reflect.Type, c jsonCoercion) (io.Reader, error) {
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(c.value(v, t))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (c jsonCoercion) value(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Types that decode themselves get exactly what was sent.
+	pt := reflect.PointerTo(t)
+	if pt.Implements(typeOfJSONUnmarshaler) || pt.Implements(typeOfTextUnmarshaler) {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if !c.numbers {
+			break
+		}
+		if s, ok := v.(string); ok {
+			n := json.Number(strings.TrimSpace(s))
+			if _, err := n.Float64(); err == nil {
+				return n
+			}
+		}
+	case reflect.String:
+		if !c.numbers {
+			break
+		}
+		if n, ok := v.(json.Number); ok {
+			return n.String()
+		}
+	case reflect.Bool:
+		if !c.bools {
+			break
+		}
+		if s, ok := v.(string); ok {
+			switch strings.TrimSpace(s) {
+			case "true", "1":
+				return true
+			case "false", "0":
+				return false
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]interface{}); ok {
+			for i, item := range arr {
+				arr[i] = c.value(item, t.Elem())
+			}
+		}
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok {
+			for k, item := range m {
+				m[k] = c.value(item, t.Elem())
+			}
+		}
+	case reflect.Struct:
+		if m, ok := v.(map[string]interface{}); ok {
+			for k, item := range m {
+				if f, ok := jsonField(t, k); ok && !hasJSONStringOption(f) {
+					m[k] = c.value(item, f.Type)
+				}
+			}
+		}
+	}
+	return v
+}
+
+// jsonField returns the field of struct type t
+// that encoding/json would decode the key into.
+// Like encoding/json, prefer an exact match of the name, then a case-insensitive match,
+// and look at the fields of embedded structs.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				if ef, ok := jsonField(et, key); ok && fold == nil {
+					fold = &ef
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if fold == nil && strings.EqualFold(name, key) {
+			fold = &f
+		}
+	}
+	if fold == nil {
+		return reflect.StructField{}, false
+	}
+	return *fold, true
+}
+
+// hasJSONStringOption returns true if the field uses the ",string" json tag option,
+// in which case encoding/json already expects the number to be quoted.
+func hasJSONStringOption(f reflect.StructField) bool {
+	_, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+	for _, o := range strings.Split(opts, ",") {
+		if o == "string" {
+			return true
+		}
+	}
+	return false
+}
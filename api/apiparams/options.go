@@ -0,0 +1,150 @@
+package apiparams
+
+import (
+	"time"
+)
+
+// Option customizes how a single Handler binds and validates parameters.
+// Options are passed to New or BindAndValidate along with the handler arguments,
+// like apiparams.BindAndValidate(adapter, &params, c, apiparams.LenientJSONNumbers()).
+// Options are removed from the handler arguments before they are passed to the Adapter.
+type Option func(*handlerOptions)
+
+type handlerOptions struct {
+	lenientJSONNumbers bool
+	lenientJSONBools   bool
+	now                func() time.Time
+	multipartMaxMemory int64
+	emptyAsUnset       bool
+	strictJSON         bool
+	customTypes        []customTypeDef
+	translateMessage   MessageTranslator
+	consumeBody        bool
+}
+
+// DefaultMultipartMaxMemory is the maximum number of bytes of a multipart form
+// that are held in memory, if MultipartMaxMemory is not used.
+const DefaultMultipartMaxMemory = 10 << 20
+
+// splitOptions separates the Options from the other handler arguments,
+// and returns the options to use, and the remaining handler arguments.
+func splitOptions(handlerArgs []interface{}) (handlerOptions, []interface{}) {
+	opts := handlerOptions{multipartMaxMemory: DefaultMultipartMaxMemory}
+	args := make([]interface{}, 0, len(handlerArgs))
+	for _, arg := range handlerArgs {
+		if o, ok := arg.(Option); ok {
+			o(&opts)
+		} else {
+			args = append(args, arg)
+		}
+	}
+	return opts, args
+}
+
+// LenientJSONNumbers allows JSON bodies to use a string for a numeric field ({"id":"123"}),
+// and a number for a string field ({"code":123}).
+// Strings that are not valid numbers still result in a 400.
+// By default, JSON types must match the field types exactly.
+func LenientJSONNumbers() Option {
+	return func(o *handlerOptions) {
+		o.lenientJSONNumbers = true
+	}
+}
+
+// LenientJSONBools allows JSON bodies to use a string for a bool field ({"pretty":"true"}).
+// The accepted strings are "true" and "1" for true, and "false" and "0" for false.
+// Other strings still result in a 400.
+// By default, JSON types must match the field types exactly.
+// This can be used with LenientJSONNumbers.
+func LenientJSONBools() Option {
+	return func(o *handlerOptions) {
+		o.lenientJSONBools = true
+	}
+}
+
+// WithNow uses now as the current time when validating,
+// like for the comparenow validator, rather than time.Now.
+// Useful for freezing time in tests.
+func WithNow(now func() time.Time) Option {
+	return func(o *handlerOptions) {
+		o.now = now
+	}
+}
+
+// MultipartMaxMemory sets the maximum number of bytes of a multipart/form-data body
+// that are held in memory while parsing (see http.Request.ParseMultipartForm).
+// File parts over this limit are stored in temporary files on disk.
+// This does not limit the size of the body itself;
+// use something like echo's BodyLimit middleware or http.MaxBytesReader for that.
+// Defaults to DefaultMultipartMaxMemory.
+func MultipartMaxMemory(n int64) Option {
+	return func(o *handlerOptions) {
+		o.multipartMaxMemory = n
+	}
+}
+
+// EmptyAsUnset treats empty query and form values, like "?s=", as unset for pointer fields,
+// so the field stays nil rather than pointing to an empty (or zero) value.
+// By default, "?s=" binds a *string field to a pointer to "",
+// which lets handlers tell "present but empty" from "not present".
+func EmptyAsUnset() Option {
+	return func(o *handlerOptions) {
+		o.emptyAsUnset = true
+	}
+}
+
+// StrictJSON rejects JSON bodies with fields that do not match a field in the parameter struct,
+// like {"bogus":1}, with a 400 naming the unknown field.
+// By default, unknown fields are ignored.
+// The JSON is decoded with encoding/json, even if a different decoder is registered for JSON.
+func StrictJSON() Option {
+	return func(o *handlerOptions) {
+		o.strictJSON = true
+	}
+}
+
+// WithCustomType registers def for only this Handler,
+// taking precedence over a global definition for the same type (see RegisterCustomType).
+// This allows customizing how a type is bound for a single endpoint,
+// and avoids leaking registrations between tests.
+func WithCustomType(def CustomTypeDef) Option {
+	expanded := def.expand()
+	return func(o *handlerOptions) {
+		o.customTypes = append(o.customTypes, expanded)
+	}
+}
+
+// ConsumeBody reads the request body without making it readable again.
+// By default, the body is kept in memory after it is read,
+// and the request's Body and GetBody are replaced,
+// so the handler (or another call to BindAndValidate) can read it again,
+// like to verify a signature.
+// Use this when nothing else reads the body, so non-JSON bodies (like XML)
+// are decoded directly from the request, and JSON bodies are not kept after binding.
+func ConsumeBody() Option {
+	return func(o *handlerOptions) {
+		o.consumeBody = true
+	}
+}
+
+// MessageTranslator returns the message to use for a validation error.
+// param is the name of the parameter (like FieldError.Param),
+// validatorName is the name of the validator that failed, like "len" (empty if not known),
+// and message is the message that would otherwise be used
+// (from the validator, or the validatemsg struct tag).
+type MessageTranslator func(param, validatorName, message string) string
+
+// TranslateMessages uses fn to replace the message of each validation error,
+// like to localize messages based on the request's Accept-Language header:
+//
+//	apiparams.BindAndValidate(adapter, &params, c, apiparams.TranslateMessages(
+//		func(param, validatorName, message string) string {
+//			return catalog.Translate(locale, validatorName, message)
+//		}))
+//
+// The translated message is used in both Messages and FieldErrors.
+func TranslateMessages(fn MessageTranslator) Option {
+	return func(o *handlerOptions) {
+		o.translateMessage = fn
+	}
+}
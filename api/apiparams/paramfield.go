@@ -15,7 +15,9 @@ import (
 // so that the Wibble field can only be set from the path and not a query parameter.
 // The exception would be the JSON param source, which can be set by any param sources.
 //
-// Possible param sources are json, path, query, and header.
+// Possible param sources are json, form, path, query, header, and file.
+// The file source is only for *multipart.FileHeader and []*multipart.FileHeader fields,
+// which are set from the files in a multipart/form-data body.
 type ParamSource string
 
 const (
@@ -24,6 +26,7 @@ const (
 	ParamSourcePath   = ParamSource("path")
 	ParamSourceQuery  = ParamSource("query")
 	ParamSourceHeader = ParamSource("header")
+	ParamSourceFile   = ParamSource("file")
 )
 
 var AllParamSources = []ParamSource{
@@ -32,6 +35,7 @@ var AllParamSources = []ParamSource{
 	ParamSourcePath,
 	ParamSourceQuery,
 	ParamSourceHeader,
+	ParamSourceFile,
 }
 
 // paramField is a container for a StructField that has some sort of parameter exposure,
@@ -61,23 +65,35 @@ type paramField struct {
 // If no paramField can be parsed (it has no tags, or the tags indicate not to export the field),
 // found is false.
 func parseToParamField(fieldDef reflect.StructField) (pf paramField, found bool) {
-	pf.StructField = fieldDef
+	pfs := parseToParamFields(fieldDef)
+	if len(pfs) == 0 {
+		return pf, false
+	}
+	return pfs[0], true
+}
+
+// parseToParamFields is like parseToParamField, but returns a paramField for every source
+// the field has a tag for, in the order of AllParamSources.
+// This allows a field to be set from multiple sources, like:
+//
+//	APIKey string `query:"api_key" header:"x-api-key"`
+func parseToParamFields(fieldDef reflect.StructField) []paramField {
+	var result []paramField
 	for _, src := range AllParamSources {
 		tag, ok := fieldDef.Tag.Lookup(string(src))
 		if !ok || tag == "-" {
 			continue
 		}
+		pf := paramField{StructField: fieldDef, Source: src}
 		parts := strings.Split(tag, ",")
 		if len(parts) > 1 && parts[0] == "" {
 			pf.Name = fieldDef.Name
 		} else {
 			pf.Name = parts[0]
 		}
-		pf.Source = src
-		found = true
-		break
+		result = append(result, pf)
 	}
-	return pf, found
+	return result
 }
 
 // CanSetFrom returns true if a parameter from source ps can be set by this paramField.
@@ -0,0 +1,66 @@
+package apiparams
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// presence tracks which struct fields the client provided a value for.
+// See Handler.Provided.
+type presence struct {
+	// fields holds the paths of struct fields (like "Note.Content") that were provided.
+	fields map[string]bool
+	// body is the JSON body, if any, and bodyType the type it was decoded into.
+	// Fields from the body are marked the first time they are needed,
+	// since it requires decoding the body again, and most callers never check presence.
+	body     []byte
+	bodyType reflect.Type
+}
+
+func newPresence() *presence {
+	return &presence{fields: make(map[string]bool)}
+}
+
+func (p *presence) Provided(fieldName string) bool {
+	if p.body != nil {
+		markJSONPresence(p.body, p.bodyType, "", p.fields)
+		p.body = nil
+	}
+	return p.fields[fieldName]
+}
+
+// markJSONPresence records, in provided, the path of every field of struct type t
+// that has a key in the JSON object in body.
+// Nested objects are walked for struct fields, so a body of {"note":{"content":""}}
+// marks both "Note" and "Note.Content".
+// This is necessary because json.Unmarshal cannot tell us whether a field
+// was omitted or explicitly set to its zero value, like {"count":0}.
+// An explicit null is treated like an omitted key, since it leaves the field unset.
+// Bodies that are not JSON objects are ignored; decoding them reports the error.
+func markJSONPresence(body []byte, t reflect.Type, prefix string, provided map[string]bool) {
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(typeOfJSONUnmarshaler) || pt.Implements(typeOfTextUnmarshaler) {
+		return
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return
+	}
+	for k, raw := range obj {
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			continue
+		}
+		f, ok := jsonField(t, k)
+		if !ok {
+			continue
+		}
+		path := prefix + f.Name
+		provided[path] = true
+		markJSONPresence(raw, f.Type, path+".", provided)
+	}
+}
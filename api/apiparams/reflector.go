@@ -3,34 +3,165 @@ package apiparams
 import (
 	"bytes"
 	"fmt"
+	"mime/multipart"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 var (
-	typeOfStringSlice = reflect.TypeOf([]string{})
-	typeOfIntSlice    = reflect.TypeOf([]int{})
+	typeOfFileHeader      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeOfFileHeaderSlice = reflect.TypeOf([]*multipart.FileHeader{})
 )
 
+// isFileType returns true if t is a type that can be set from uploaded files.
+// These fields are set by binder.setFromFiles, and not from string values.
+func isFileType(t reflect.Type) bool {
+	return t == typeOfFileHeader || t == typeOfFileHeaderSlice
+}
+
 // reflector holds as much of the reflection code as possible, because reflection is hard.
 type reflector struct {
 	pointerValue, underlyingValue reflect.Value
 	paramFieldsByJsonName         map[string]paramField
 	jsonNamesByFieldName          map[string]string
 	typeParsers                   map[reflect.Type]Parser
+	// embedDepthsByJsonName tracks how deeply embedded each top-level
+	// (including promoted) parameter is, so we can resolve collisions.
+	// See parseStructTags.
+	embedDepthsByJsonName map[string]int
+	// jsonOnly is true if the struct has only json-sourced fields, and no defaults,
+	// so binding can skip the work for other sources. See isJSONOnly.
+	jsonOnly bool
+	// bodyField is the index of the field with a body tag, which the whole body is decoded into,
+	// or nil if the body is decoded into the struct itself. See findBodyField.
+	bodyField []int
+}
+
+// parsedStruct is the result of parsing the struct tags of a parameter struct type.
+// It is cached in parsedStructs, since it only depends on the type,
+// and the maps are never modified after parsing.
+type parsedStruct struct {
+	paramFieldsByJsonName map[string]paramField
+	jsonNamesByFieldName  map[string]string
+	embedDepthsByJsonName map[string]int
+	jsonOnly              bool
+	bodyField             []int
 }
 
+var parsedStructs sync.Map
+
+// MaxStructDepth is how deeply structs can be nested in a parameter struct
+// (through struct fields, slices of structs, and embedded structs).
+// Parsing a deeper struct panics, rather than overflowing the stack
+// if the struct is recursive.
+var MaxStructDepth = 32
+
 func newReflector(paramsStructPtr interface{}) reflector {
 	pointerValue := reflect.ValueOf(paramsStructPtr)
-	r := reflector{
+	underlyingValue := pointerValue.Elem()
+	ps := parseStruct(underlyingValue.Type())
+	return reflector{
 		pointerValue,
-		pointerValue.Elem(),
-		make(map[string]paramField),
-		make(map[string]string),
+		underlyingValue,
+		ps.paramFieldsByJsonName,
+		ps.jsonNamesByFieldName,
 		make(map[reflect.Type]Parser),
+		ps.embedDepthsByJsonName,
+		ps.jsonOnly,
+		ps.bodyField,
 	}
-	r.parseStructTags(r.underlyingValue.Type())
-	return r
+}
+
+// parseStruct returns the parsedStruct for t, parsing it the first time t is seen.
+func parseStruct(t reflect.Type) parsedStruct {
+	if ps, ok := parsedStructs.Load(t); ok {
+		return ps.(parsedStruct)
+	}
+	r := reflector{
+		paramFieldsByJsonName: make(map[string]paramField),
+		jsonNamesByFieldName:  make(map[string]string),
+		embedDepthsByJsonName: make(map[string]int),
+	}
+	r.parseStructTags(t, 0, 0)
+	ps := parsedStruct{
+		paramFieldsByJsonName: r.paramFieldsByJsonName,
+		jsonNamesByFieldName:  r.jsonNamesByFieldName,
+		embedDepthsByJsonName: r.embedDepthsByJsonName,
+		jsonOnly:              isJSONOnly(t, map[reflect.Type]bool{}),
+		bodyField:             findBodyField(t),
+	}
+	parsedStructs.Store(t, ps)
+	return ps
+}
+
+// isJSONOnly returns true if no fields of struct type t (including nested and embedded structs)
+// have a default, or a tag for a source other than json.
+// Such structs can only be bound from the body (or from other sources using json names),
+// so binding does not need to set defaults.
+func isJSONOnly(t reflect.Type, seen map[reflect.Type]bool) bool {
+	t = derefType(t)
+	if t.Kind() == reflect.Slice {
+		t = derefType(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return true
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, ok := f.Tag.Lookup("default"); ok {
+			return false
+		}
+		for _, src := range AllParamSources {
+			if _, ok := f.Tag.Lookup(string(src)); ok && src != ParamSourceJSON {
+				return false
+			}
+		}
+		if !isJSONOnly(f.Type, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyTag is the struct tag for a field that receives the entire request body,
+// like a top-level JSON array. Its value is the name used for the field in errors.
+const bodyTag = "body"
+
+// findBodyField returns the index of the top-level field of struct type t with a body tag,
+// or nil if there is none.
+// Panic if there is more than one, since the body can only be decoded into one field.
+func findBodyField(t reflect.Type) []int {
+	var index []int
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup(bodyTag); !ok {
+			continue
+		}
+		if index != nil {
+			panic(fmt.Sprintf("apiparams: parameter struct %v has multiple fields with a %q tag", t, bodyTag))
+		}
+		index = []int{i}
+	}
+	return index
+}
+
+// BodyPointer returns a pointer to what the request body should be decoded into:
+// the field with a body tag, if there is one, or the parameter struct.
+func (r reflector) BodyPointer() interface{} {
+	if r.bodyField == nil {
+		return r.Pointer()
+	}
+	return r.underlyingValue.FieldByIndex(r.bodyField).Addr().Interface()
+}
+
+// BodyType returns the type of what BodyPointer points to.
+func (r reflector) BodyType() reflect.Type {
+	if r.bodyField == nil {
+		return r.underlyingValue.Type()
+	}
+	return r.underlyingValue.Type().FieldByIndex(r.bodyField).Type
 }
 
 func (r reflector) RegisterParser(t reflect.Type, p Parser) {
@@ -64,19 +195,54 @@ func (r reflector) FieldFor(fd reflect.StructField) reflect.Value {
 // MapFieldNameToParamName convert a field name string ("Foo") or path ("Foo.Bar" or "Foo[0].Bar")
 // to a parameter name string ("foo", "foo.bar", "foo[0].bar",
 // whatever was set up in struct tags).
-func (r reflector) MapFieldNameToParamName(fieldName string) string {
+//
+// boundNames maps top-level field names to the parameter name they were actually bound from,
+// for fields that can be set from multiple sources (see parseToParamFields).
+// If a top-level field is not in boundNames, the name from its first struct tag is used.
+func (r reflector) MapFieldNameToParamName(fieldName string, boundNames map[string]string) string {
 	fm := fieldMapper{
 		r.jsonNamesByFieldName,
+		boundNames,
 		bytes.NewBuffer(nil),
 		make([]byte, 0),
 	}
 	return fm.Map(fieldName)
 }
 
+// validateMsgTag is the struct tag for a custom validation error message for a field.
+const validateMsgTag = "validatemsg"
+
+// ValidateMessageFor returns the validatemsg tag of the field at a field name path
+// (like "Foo", "Foo.Bar", or "Foo[0].Bar", as used in validation errors),
+// and true if the field has the tag.
+func (r reflector) ValidateMessageFor(fieldName string) (string, bool) {
+	t := r.underlyingValue.Type()
+	var field reflect.StructField
+	for _, part := range strings.Split(fieldName, ".") {
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			part = part[:i]
+		}
+		t = derefType(t)
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = derefType(t.Elem())
+		}
+		if t.Kind() != reflect.Struct {
+			return "", false
+		}
+		f, ok := t.FieldByName(part)
+		if !ok {
+			return "", false
+		}
+		field, t = f, f.Type
+	}
+	return field.Tag.Lookup(validateMsgTag)
+}
+
 type fieldMapper struct {
-	lookup map[string]string
-	buffer *bytes.Buffer
-	run    []byte
+	lookup      map[string]string
+	firstLookup map[string]string
+	buffer      *bytes.Buffer
+	run         []byte
 }
 
 func (f *fieldMapper) Map(fieldName string) string {
@@ -105,7 +271,10 @@ func (f *fieldMapper) mapAndFlushRun() {
 	if len(f.run) == 0 {
 		return
 	}
-	mapped := f.lookup[string(f.run)]
+	mapped, ok := f.firstLookup[string(f.run)]
+	if !ok || f.buffer.Len() > 0 {
+		mapped = f.lookup[string(f.run)]
+	}
 	f.buffer.WriteString(mapped)
 	f.run = make([]byte, 0)
 }
@@ -147,29 +316,99 @@ func (f *fieldMapper) mapAndFlushRun() {
 //     or write yet-another-validator that is consistent with the way we parse names
 //     from struct tags.
 //     See the MapFieldNameToParamName method doc for more details on how this works.
-func (r reflector) parseStructTags(underlyingType reflect.Type) {
+//
+// Fields of anonymous embedded structs are promoted into the outer struct,
+// so they can collide with the param names of outer fields.
+// Like Go and encoding/json, the least-embedded field wins;
+// for example, an outer field named "id" is used over an "id" field from an embedded struct.
+// If fields at the same embedding depth have the same param name,
+// the parameter struct is ambiguous, and we panic.
+//
+// embedDepth is the embedding depth of the fields in underlyingType,
+// or -1 if the fields are not top-level (they are in a nested struct),
+// in which case they are not checked for collisions.
+//
+// depth is how many structs deep underlyingType is in the parameter struct.
+// If it is over MaxStructDepth, we panic, since the struct is probably recursive
+// (like a Children []Node field of Node) and would otherwise overflow the stack.
+func (r reflector) parseStructTags(underlyingType reflect.Type, embedDepth, depth int) {
+	if depth > MaxStructDepth {
+		panic(fmt.Sprintf(
+			"apiparams: parameter struct is nested more than %d levels deep at %v; "+
+				"it may be recursive (see MaxStructDepth)",
+			MaxStructDepth, underlyingType))
+	}
+	nestedDepth := -1
+	if embedDepth >= 0 {
+		nestedDepth = embedDepth + 1
+	}
 	for i := 0; i < underlyingType.NumField(); i++ {
 		fieldDef := underlyingType.Field(i)
 		if fieldDef.Anonymous {
-			r.parseStructTags(fieldDef.Type)
+			r.parseStructTags(fieldDef.Type, nestedDepth, depth+1)
 		}
-		paramField, ok := parseToParamField(fieldDef)
-		if !ok {
+		if name, ok := fieldDef.Tag.Lookup(bodyTag); ok && embedDepth == 0 {
+			// The body field is not a parameter, but we need its name for errors.
+			r.jsonNamesByFieldName[fieldDef.Name] = name
+			r.parseNestedStructTags(fieldDef.Type, depth)
+			continue
+		}
+		paramFields := parseToParamFields(fieldDef)
+		if len(paramFields) == 0 {
+			continue
+		}
+		registered := false
+		for i, paramField := range paramFields {
+			if i > 0 && paramField.Name == paramFields[0].Name {
+				// Same name from another source, like `json:"id" query:"id"`; the first source is used.
+				continue
+			}
+			if r.registerParamField(underlyingType, paramField, embedDepth) {
+				registered = true
+			}
+		}
+		if !registered {
 			continue
 		}
-		r.paramFieldsByJsonName[paramField.Name] = paramField
-		r.jsonNamesByFieldName[fieldDef.Name] = paramField.Name
-
-		switch fieldDef.Type.Kind() {
-		case reflect.Struct:
-			r.parseStructTags(fieldDef.Type)
-		case reflect.Slice:
-			sliceElementType := fieldDef.Type.Elem()
-			if sliceElementType.Kind() == reflect.Struct {
-				r.parseStructTags(sliceElementType)
+		r.jsonNamesByFieldName[fieldDef.Name] = paramFields[0].Name
+		r.parseNestedStructTags(fieldDef.Type, depth)
+	}
+}
+
+// parseNestedStructTags parses the struct tags of a field of type t,
+// if it is a struct or slice of structs.
+func (r reflector) parseNestedStructTags(t reflect.Type, depth int) {
+	switch t.Kind() {
+	case reflect.Struct:
+		r.parseStructTags(t, -1, depth+1)
+	case reflect.Slice:
+		sliceElementType := t.Elem()
+		if sliceElementType.Kind() == reflect.Struct {
+			r.parseStructTags(sliceElementType, -1, depth+1)
+		}
+	}
+}
+
+// registerParamField adds paramField to paramFieldsByJsonName,
+// resolving collisions as described in parseStructTags.
+// Return false if paramField collides with a less-embedded field, and is not registered.
+func (r reflector) registerParamField(underlyingType reflect.Type, paramField paramField, embedDepth int) bool {
+	if embedDepth >= 0 {
+		if existingDepth, found := r.embedDepthsByJsonName[paramField.Name]; found {
+			if existingDepth < embedDepth {
+				// A less-embedded field already has this name, so it wins.
+				return false
+			}
+			if existingDepth == embedDepth {
+				panic(fmt.Sprintf(
+					"apiparams: parameter struct %v has multiple fields named %q at the same embedding depth",
+					underlyingType, paramField.Name))
 			}
 		}
+		r.embedDepthsByJsonName[paramField.Name] = embedDepth
 	}
+	r.paramFieldsByJsonName[paramField.Name] = paramField
+	return true
 }
 
 // Set a struct field, parsing/coercing value into the right type.
@@ -255,6 +494,29 @@ func (r reflector) parseValue(t reflect.Type, field reflect.Value, value string)
 		}
 		return reflect.ValueOf(v), err
 
+	case reflect.Uint:
+		temp, err := strconv.ParseUint(value, 10, 64)
+		v := uint(temp)
+		if isPtr {
+			return reflect.ValueOf(&v), err
+		}
+		return reflect.ValueOf(v), err
+
+	case reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, 64)
+		if isPtr {
+			return reflect.ValueOf(&v), err
+		}
+		return reflect.ValueOf(v), err
+
+	case reflect.Uint32:
+		temp, err := strconv.ParseUint(value, 10, 32)
+		v := uint32(temp)
+		if isPtr {
+			return reflect.ValueOf(&v), err
+		}
+		return reflect.ValueOf(v), err
+
 	case reflect.String:
 		if isPtr {
 			return reflect.ValueOf(&value), nil
@@ -304,27 +566,29 @@ func (r reflector) parseValue(t reflect.Type, field reflect.Value, value string)
 		// This would fail if sliceVal is nil; see comment above about why we initialize it.
 		newSliceVal := reflect.Append(sliceVal, elementVal)
 
-		// Now we're back to the verbose "if ptr" duplication.
-		switch fieldValueType {
-		case typeOfStringSlice:
-			if isPtr {
-				i := newSliceVal.Interface().([]string)
-				return reflect.ValueOf(&i), nil
-			}
-			return newSliceVal, nil
-		case typeOfIntSlice:
-			if isPtr {
-				i := newSliceVal.Interface().([]int)
-				return reflect.ValueOf(&i), nil
-			}
-			return newSliceVal, nil
+		// Use the field's slice type, so this works for any element type we can parse,
+		// including registered custom types.
+		if isPtr {
+			ptr := reflect.New(fieldValueType)
+			ptr.Elem().Set(newSliceVal)
+			return ptr, nil
 		}
+		return newSliceVal, nil
 	}
 
 	panicUnsupportedType(t)
 	panic("unreachable")
 }
 
+// derefType returns the type t points to, or t if it is not a pointer type.
+// Parsers and defaulters are registered for the non-pointer type.
+func derefType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
 func panicUnsupportedType(t reflect.Type) {
 	panic(fmt.Sprintf(
 		"parameter struct has parsed field with type %v, kind %v; "+
@@ -0,0 +1,141 @@
+package apiparams
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var supportedBasicTypes = map[reflect.Type]bool{
+	reflect.TypeOf(int(0)):     true,
+	reflect.TypeOf(int32(0)):   true,
+	reflect.TypeOf(int64(0)):   true,
+	reflect.TypeOf(uint(0)):    true,
+	reflect.TypeOf(uint32(0)):  true,
+	reflect.TypeOf(uint64(0)):  true,
+	reflect.TypeOf(float32(0)): true,
+	reflect.TypeOf(float64(0)): true,
+	reflect.TypeOf(false):      true,
+	reflect.TypeOf(""):         true,
+}
+
+// ValidateStruct checks that every field of the parameter struct that can be bound from a string
+// (path, query, header, and form params, and `default` tags) has a type that apiparams can parse,
+// and that every `default` tag can be parsed.
+// Otherwise, these problems are only found (as a panic) when a request binds the field.
+// Call it from a unit test for each parameter struct, like:
+//
+//	Expect(apiparams.ValidateStruct(&CreateUserParams{})).To(Succeed())
+//
+// Fields with a json tag can also be bound from other params,
+// but if they are of a complex type (struct, map, or slice of those),
+// they are assumed to be bound only from the body and are not checked.
+//
+// Custom types registered with RegisterCustomType are supported.
+// Returns all problems found, joined into a single error.
+func ValidateStruct(paramsStructPtr interface{}) (err error) {
+	v := reflect.ValueOf(paramsStructPtr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("apiparams: expected a pointer to a struct, got %T", paramsStructPtr)
+	}
+	defer func() {
+		// Parsing the struct tags can panic for invalid structs, like ambiguous field names.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	ref := newReflector(paramsStructPtr)
+	defaulters := make(map[reflect.Type]Defaulter, len(defaultCustomTypes))
+	for _, def := range defaultCustomTypes {
+		ref.RegisterParser(def.Type, def.Parser)
+		defaulters[def.Type] = def.Defaulter
+	}
+	var errs []error
+	names := make([]string, 0, len(ref.paramFieldsByJsonName))
+	for name := range ref.paramFieldsByJsonName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pf := ref.paramFieldsByJsonName[name]
+		if pf.Source == ParamSourceFile && !isFileType(pf.StructField.Type) {
+			errs = append(errs, fmt.Errorf(
+				"apiparams: field %s has type %v, but file params must be *multipart.FileHeader or []*multipart.FileHeader",
+				pf.StructField.Name, pf.StructField.Type))
+			continue
+		}
+		if ref.canParse(pf.StructField.Type) || isFileType(pf.StructField.Type) {
+			continue
+		}
+		if pf.Source != ParamSourceJSON || !isComplexType(pf.StructField.Type) {
+			errs = append(errs, fmt.Errorf(
+				"apiparams: field %s has type %v, which cannot be bound from %s params",
+				pf.StructField.Name, pf.StructField.Type, pf.Source))
+		}
+	}
+	errs = append(errs, ref.validateDefaults(v.Elem().Type(), defaulters)...)
+	return errors.Join(errs...)
+}
+
+// validateDefaults returns an error for each field with a `default` tag that cannot be parsed,
+// recursing into nested structs like binder.setFromDefaults.
+func (r reflector) validateDefaults(t reflect.Type, defaulters map[reflect.Type]Defaulter) []error {
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		fieldDef := t.Field(i)
+		if fieldDef.Type.Kind() == reflect.Struct {
+			errs = append(errs, r.validateDefaults(fieldDef.Type, defaulters)...)
+		}
+		defaultValue := fieldDef.Tag.Get("default")
+		if defaultValue == "" {
+			continue
+		}
+		if !r.canParse(fieldDef.Type) {
+			errs = append(errs, fmt.Errorf(
+				"apiparams: field %s has type %v, which cannot be set from a default",
+				fieldDef.Name, fieldDef.Type))
+			continue
+		}
+		if err := r.validateDefault(fieldDef, defaultValue, defaulters[derefType(fieldDef.Type)]); err != nil {
+			errs = append(errs, fmt.Errorf("apiparams: field %s has invalid default %q: %v", fieldDef.Name, defaultValue, err))
+		}
+	}
+	return errs
+}
+
+func (r reflector) validateDefault(fieldDef reflect.StructField, value string, defaulter Defaulter) (err error) {
+	defer func() {
+		// Defaulters may panic for invalid values.
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	if defaulter != nil {
+		value = defaulter(value)
+	}
+	_, err = r.parseValue(fieldDef.Type, reflect.New(fieldDef.Type).Elem(), value)
+	return err
+}
+
+// canParse returns true if parseValue supports t.
+// Slices are supported if their elements are (but not slices of slices).
+func (r reflector) canParse(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if r.typeParsers[t] != nil {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		return t.Elem().Kind() != reflect.Slice && r.canParse(t.Elem())
+	}
+	return supportedBasicTypes[t]
+}
+
+func isComplexType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// IsCorsPreflight returns true if req is a CORS preflight request:
+// an OPTIONS request with Origin and Access-Control-Request-Method headers.
+func IsCorsPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions &&
+		req.Header.Get(echo.HeaderOrigin) != "" &&
+		req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+}
+
+// corsPreflightLoggingMiddleware adds cors_preflight=true to the request logger
+// for CORS preflight requests, so they can be told apart from other OPTIONS requests.
+// It must be used inside LoggingMiddleware, and before echo's CORS middleware
+// (which responds to preflight requests without calling further handlers).
+func corsPreflightLoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if IsCorsPreflight(c.Request()) {
+			SetLogger(c, Logger(c).With("cors_preflight", true))
+		}
+		return next(c)
+	}
+}
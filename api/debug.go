@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"runtime"
 	"sync/atomic"
+	"time"
 )
 
 type DebugMiddlewareConfig struct {
@@ -16,9 +17,17 @@ type DebugMiddlewareConfig struct {
 	DumpRequestHeaders  bool
 	DumpResponseHeaders bool
 	DumpAll             bool
+	// Names of headers that are never dumped.
+	// If nil, use the headers configured for LoggingMiddleware
+	// (see LoggingMiddlwareConfig.SensitiveHeaders), or DefaultSensitiveHeaders.
+	SensitiveHeaders []string
 	// Log out memory stats every 'n' requests.
 	// If <= 0, do not log them.
 	DumpMemoryEvery int
+	// Log out memory stats at most once per this duration, regardless of request rate.
+	// Reading memory stats stops the world, so this keeps its cost from scaling with traffic.
+	// Can be used along with DumpMemoryEvery. If <= 0, do not log them.
+	DumpMemoryEveryDuration time.Duration
 }
 
 func DebugMiddleware(cfg DebugMiddlewareConfig) echo.MiddlewareFunc {
@@ -35,11 +44,36 @@ func DebugMiddleware(cfg DebugMiddlewareConfig) echo.MiddlewareFunc {
 		cfg.DumpResponseHeaders = true
 		cfg.DumpResponseBody = true
 	}
+	var configuredSensitive headerSet
+	if cfg.SensitiveHeaders != nil {
+		configuredSensitive = newHeaderSet(cfg.SensitiveHeaders)
+	}
 	var requestCounter uint64
 	dumpEveryUint := uint64(cfg.DumpMemoryEvery)
+	// Unix nanoseconds of the last time-based memory dump.
+	var lastMemoryDump int64
+	shouldDumpMemory := func(requestNum uint64) bool {
+		if cfg.DumpMemoryEvery > 0 && (requestNum%dumpEveryUint) == 0 {
+			return true
+		}
+		if cfg.DumpMemoryEveryDuration > 0 {
+			now := time.Now().UnixNano()
+			last := atomic.LoadInt64(&lastMemoryDump)
+			// Only one of any concurrent requests can win the swap,
+			// so only one of them reads memory stats for the interval.
+			if now-last >= int64(cfg.DumpMemoryEveryDuration) && atomic.CompareAndSwapInt64(&lastMemoryDump, last, now) {
+				return true
+			}
+		}
+		return false
+	}
 	bd := middleware.BodyDump(func(c echo.Context, reqBody []byte, resBody []byte) {
-		atomic.AddUint64(&requestCounter, 1)
+		requestNum := atomic.AddUint64(&requestCounter, 1)
 		log := logctx.Logger(StdContext(c))
+		sensitive := configuredSensitive
+		if sensitive == nil {
+			sensitive = sensitiveHeaders(c)
+		}
 		if cfg.DumpRequestBody {
 			log = log.With("debug_request_body", string(reqBody))
 		}
@@ -47,12 +81,12 @@ func DebugMiddleware(cfg DebugMiddlewareConfig) echo.MiddlewareFunc {
 			log = log.With("debug_response_body", string(resBody))
 		}
 		if cfg.DumpRequestHeaders {
-			log = log.With("debug_request_headers", headerToMap(c.Request().Header))
+			log = log.With("debug_request_headers", headerToMap(c.Request().Header, sensitive))
 		}
 		if cfg.DumpResponseHeaders {
-			log = log.With("debug_response_headers", headerToMap(c.Response().Header()))
+			log = log.With("debug_response_headers", headerToMap(c.Response().Header(), sensitive))
 		}
-		if cfg.DumpMemoryEvery > 0 && (requestCounter%dumpEveryUint) == 0 {
+		if shouldDumpMemory(requestNum) {
 			var ms runtime.MemStats
 			runtime.ReadMemStats(&ms)
 			log = log.With(
@@ -81,10 +115,12 @@ func DebugMiddleware(cfg DebugMiddlewareConfig) echo.MiddlewareFunc {
 	return bd
 }
 
-func headerToMap(h http.Header) map[string]string {
+func headerToMap(h http.Header, skip headerSet) map[string]string {
 	r := make(map[string]string, len(h))
 	for k := range h {
-		r[k] = h.Get(k)
+		if !skip.Has(k) {
+			r[k] = h.Get(k)
+		}
 	}
 	return r
 }
@@ -2,6 +2,9 @@ package echoapitest
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/lithictech/go-aperitif/v2/api"
+	"github.com/lithictech/go-aperitif/v2/logctx"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 )
@@ -11,3 +14,28 @@ func Serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
 	e.ServeHTTP(rr, req)
 	return rr
 }
+
+// App is an echo app created with api.New, which logs to a null logger,
+// so tests can make assertions about what was logged.
+type App struct {
+	*echo.Echo
+	Logger *slog.Logger
+	Hook   *logctx.Hook
+}
+
+// NewApp returns an App using api.New with the given config.
+// cfg.Logger is replaced with a null logger.
+func NewApp(cfg api.Config) *App {
+	logger, hook := logctx.NewNullLogger()
+	cfg.Logger = logger
+	return &App{Echo: api.New(cfg), Logger: logger, Hook: hook}
+}
+
+// ServeAndCapture serves req, and returns the response,
+// and the records logged while serving it.
+// Records logged asynchronously, after the request is finished, are not included.
+func (a *App) ServeAndCapture(req *http.Request) (*httptest.ResponseRecorder, []logctx.HookRecord) {
+	before := len(a.Hook.Records())
+	rr := Serve(a.Echo, req)
+	return rr, a.Hook.Records()[before:]
+}
@@ -2,15 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/lithictech/go-aperitif/v2/api/apiparams"
 	"net/http"
 )
 
 type Error struct {
-	HTTPStatus int
-	ErrorCode  string
-	Message    string
-	Original   error
+	HTTPStatus int    `json:"http_status" xml:"http_status"`
+	ErrorCode  string `json:"error_code" xml:"error_code"`
+	Message    string `json:"message" xml:"message"`
+	Original   error  `json:"-" xml:"-"`
+	// Headers are written to the response by NewHTTPErrorHandler,
+	// like Retry-After for a 429 or ETag for a 304.
+	Headers map[string]string `json:"-" xml:"-"`
 }
 
 func (e Error) Error() string {
@@ -37,6 +43,32 @@ func (e Error) MarshalJSON() ([]byte, error) {
 	return json.Marshal(e.ToMap())
 }
 
+// WithHeader returns a copy of the error that will write the given response header.
+func (e Error) WithHeader(key, value string) Error {
+	headers := make(map[string]string, len(e.Headers)+1)
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+	headers[key] = value
+	e.Headers = headers
+	return e
+}
+
+// MarshalXML renders the error as an <error> element,
+// with the same fields as MarshalJSON.
+func (e Error) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	type plainError Error
+	v := struct {
+		plainError
+		Original string `xml:"original,omitempty"`
+	}{plainError: plainError(e)}
+	if e.Original != nil {
+		v.Original = e.Original.Error()
+	}
+	start.Name = xml.Name{Local: "error"}
+	return enc.EncodeElement(v, start)
+}
+
 func NewError(httpStatus int, errorCode string, original ...error) Error {
 	e := Error{
 		ErrorCode:  errorCode,
@@ -52,3 +84,20 @@ func NewError(httpStatus int, errorCode string, original ...error) Error {
 func NewInternalError(original ...error) Error {
 	return NewError(500, "internal_error", original...)
 }
+
+// FromApiparamsError converts an error from apiparams.BindAndValidate into an Error,
+// the same way the error handling middleware does.
+// Handlers can use this to add context to a binding error before returning it.
+func FromApiparamsError(e apiparams.HTTPError) Error {
+	apiErr := NewError(e.Code(), "validation", e)
+	apiErr.Message = e.Error()
+	return apiErr
+}
+
+// FromEchoError converts an *echo.HTTPError into an Error,
+// the same way the error handling middleware does.
+func FromEchoError(e *echo.HTTPError) Error {
+	apiErr := NewError(e.Code, "echo", e.Internal)
+	apiErr.Message = fmt.Sprintf("%v", e.Message)
+	return apiErr
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrorDef is an error code declared once, along with its default status and message.
+// Use ErrorCatalog.Define to create it, and New or Newf to create an Error from it.
+type ErrorDef struct {
+	Code       string
+	HTTPStatus int
+	Message    string
+}
+
+// New returns an Error with the code, status, and message of the definition.
+func (d ErrorDef) New(original ...error) Error {
+	e := NewError(d.HTTPStatus, d.Code, original...)
+	e.Message = d.Message
+	return e
+}
+
+// Newf returns an Error like New, but with a formatted message.
+func (d ErrorDef) Newf(format string, args ...interface{}) Error {
+	e := d.New()
+	e.Message = fmt.Sprintf(format, args...)
+	return e
+}
+
+// Is returns true if err is (or wraps) an Error with the code of this definition.
+func (d ErrorDef) Is(err error) bool {
+	var apiErr Error
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == d.Code
+}
+
+// ErrorCatalog is a registry of error codes, so the codes an API can return
+// are declared in one place, rather than as strings passed to NewError.
+// Usually a service has a single catalog, with definitions as package variables:
+//
+//	var Errors = api.NewErrorCatalog()
+//	var errNotFound = Errors.Define(404, "not_found", "")
+//
+//	func NotFound(what string) api.Error {
+//		return errNotFound.Newf("%s not found", what)
+//	}
+type ErrorCatalog struct {
+	defs []ErrorDef
+	mux  sync.RWMutex
+}
+
+func NewErrorCatalog() *ErrorCatalog {
+	return &ErrorCatalog{}
+}
+
+// Define adds an error code to the catalog and returns its definition.
+// If message is empty, use the status text of the HTTP status.
+// Panics if the code is already defined, since that is a programming error.
+func (c *ErrorCatalog) Define(httpStatus int, code, message string) ErrorDef {
+	if message == "" {
+		message = http.StatusText(httpStatus)
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, d := range c.defs {
+		if d.Code == code {
+			panic(fmt.Sprintf("api: error code %q is already defined", code))
+		}
+	}
+	d := ErrorDef{Code: code, HTTPStatus: httpStatus, Message: message}
+	c.defs = append(c.defs, d)
+	return d
+}
+
+// Lookup returns the definition for the error code, and true if it exists.
+func (c *ErrorCatalog) Lookup(code string) (ErrorDef, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	for _, d := range c.defs {
+		if d.Code == code {
+			return d, true
+		}
+	}
+	return ErrorDef{}, false
+}
+
+// Defs returns all definitions in the catalog, in the order they were defined.
+// Useful for documenting the error codes of an API, or testing them.
+func (c *ErrorCatalog) Defs() []ErrorDef {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return append([]ErrorDef(nil), c.defs...)
+}
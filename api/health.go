@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/lithictech/go-aperitif/v2/logctx"
+	"github.com/lithictech/go-aperitif/v2/stopwatch"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthCheck checks whether a dependency (like a database) is available.
+// Return an error if it is not.
+type HealthCheck func(ctx context.Context) error
+
+// HealthCheckResult is the result of a single HealthCheck,
+// as rendered by NewHealthHandler.
+type HealthCheckResult struct {
+	Ok        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DefaultHealthCheckTimeout is used when HealthConfig.Timeout is not set.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+type HealthConfig struct {
+	// Checks maps the name of each check to the check to run.
+	Checks map[string]HealthCheck
+	// Timeout is how long each check may take before it fails.
+	// Defaults to DefaultHealthCheckTimeout.
+	Timeout time.Duration
+}
+
+// NewHealthHandler returns a handler (usually used as Config.HealthHandler)
+// that runs each of the named checks, and renders a JSON object
+// mapping each name to its HealthCheckResult.
+// See NewHealthHandlerWithConfig.
+func NewHealthHandler(checks map[string]HealthCheck) echo.HandlerFunc {
+	return NewHealthHandlerWithConfig(HealthConfig{Checks: checks})
+}
+
+// NewHealthHandlerWithConfig returns a handler that runs each of cfg.Checks.
+// Checks run concurrently, and each is timed with a stopwatch.
+// Each check is passed a context that is done after cfg.Timeout;
+// a check that has not returned by then fails, even if it ignores the context,
+// so one hung dependency cannot block the handler.
+// A check that panics fails with the panic as its error.
+// Respond with a 200 if all checks pass, or a 503 otherwise.
+func NewHealthHandlerWithConfig(cfg HealthConfig) echo.HandlerFunc {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHealthCheckTimeout
+	}
+	return func(c echo.Context) error {
+		ctx := logctx.WithLogger(c.Request().Context(), Logger(c))
+		status := http.StatusOK
+		results := make(map[string]HealthCheckResult, len(cfg.Checks))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range cfg.Checks {
+			wg.Add(1)
+			go func(name string, check HealthCheck) {
+				defer wg.Done()
+				result := runHealthCheck(ctx, Logger(c), name, check, cfg.Timeout)
+				mu.Lock()
+				defer mu.Unlock()
+				if !result.Ok {
+					status = http.StatusServiceUnavailable
+				}
+				results[name] = result
+			}(name, check)
+		}
+		wg.Wait()
+		return c.JSON(status, results)
+	}
+}
+
+func runHealthCheck(ctx context.Context, logger *slog.Logger, name string, check HealthCheck, timeout time.Duration) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	sw := stopwatch.StartWith(ctx, logger, "health_check_"+name, stopwatch.StartOpts{Level: slog.LevelDebug})
+	// Buffered so the check's goroutine can exit if we stop waiting for it.
+	done := make(chan error, 1)
+	go func() {
+		// The check runs outside of the request goroutine, so a panic would not be
+		// recovered by LoggingMiddleware and would crash the server.
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := HealthCheckResult{Ok: err == nil, LatencyMs: sw.Elapsed().Milliseconds()}
+	if err == nil {
+		sw.FinishWith(ctx, stopwatch.FinishOpts{Level: slog.LevelDebug, Milliseconds: true})
+	} else {
+		result.Error = err.Error()
+	}
+	return result
+}
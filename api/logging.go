@@ -6,10 +6,14 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/lithictech/go-aperitif/v2/api/apiparams"
 	"github.com/lithictech/go-aperitif/v2/logctx"
+	"github.com/lithictech/go-aperitif/v2/stringutil"
 	"log/slog"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,15 +30,81 @@ func SetLogger(c echo.Context, logger *slog.Logger) {
 	c.Set(logctx.LoggerKey, logger)
 }
 
+// AddLogFields adds args to the request logger, and returns the new logger.
+// Unlike Logger(c).With(args...), the fields are kept for the rest of the request,
+// including later calls to Logger and the request_finished log line.
+// This is the echo.Context equivalent of logctx.AddToR.
+func AddLogFields(c echo.Context, args ...any) *slog.Logger {
+	logger := Logger(c).With(args...)
+	SetLogger(c, logger)
+	return logger
+}
+
+// DefaultSensitiveHeaders are the names of headers that are never logged
+// by LoggingMiddleware or DebugMiddleware, unless other names are configured.
+var DefaultSensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+const sensitiveHeadersKey = "sensitive-headers"
+
+// headerSet is a set of canonicalized header names.
+type headerSet map[string]struct{}
+
+func newHeaderSet(names []string) headerSet {
+	hs := make(headerSet, len(names))
+	for _, n := range names {
+		hs[http.CanonicalHeaderKey(n)] = struct{}{}
+	}
+	return hs
+}
+
+func (hs headerSet) Has(name string) bool {
+	_, ok := hs[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// withHeaderGroup adds the (first) value of each non-sensitive header in h
+// to the logger under a group with the given name, like request_header.User-Agent.
+// Using a group, rather than flat keys, allows handlers to process the headers structurally.
+func withHeaderGroup(logger *slog.Logger, group string, h http.Header, skip headerSet) *slog.Logger {
+	keys := make([]string, 0, len(h))
+	for k, v := range h {
+		if len(v) > 0 && !skip.Has(k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return logger
+	}
+	sort.Strings(keys)
+	attrs := make([]any, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.String(k, h[k][0])
+	}
+	return logger.With(slog.Group(group, attrs...))
+}
+
+// sensitiveHeaders returns the header set stored in the context by LoggingMiddleware,
+// or the DefaultSensitiveHeaders if the middleware is not in use.
+func sensitiveHeaders(c echo.Context) headerSet {
+	if hs, ok := c.Get(sensitiveHeadersKey).(headerSet); ok {
+		return hs
+	}
+	return newHeaderSet(DefaultSensitiveHeaders)
+}
+
 type LoggingMiddlwareConfig struct {
-	// If true, log request headers.
+	// If true, log request headers, under the "request_header" group.
 	RequestHeaders bool
-	// If true, log response headers.
+	// If true, log response headers, under the "response_header" group.
 	ResponseHeaders bool
 	// If true, do not log trace_id to the logs.
 	// Use this when doing your own trace logging, like with logctx.TracingHandler.
 	// Note that the trace ID for the request is still available in the request.
 	SkipTraceAttrs bool
+	// Names of request and response headers that are never logged.
+	// The set is also stored in the request context, and used by DebugMiddleware.
+	// If nil, use DefaultSensitiveHeaders. Use an empty slice to log all headers.
+	SensitiveHeaders []string
 
 	// If provided, the returned logger is stored in the context
 	// which is eventually passed to the handler.
@@ -43,11 +113,33 @@ type LoggingMiddlwareConfig struct {
 	// If provided, the returned logger is used for response logging.
 	// Use to add additional fields to the logger based on the request or response.
 	AfterRequest func(echo.Context, *slog.Logger) *slog.Logger
+	// Additional BeforeRequest functions, called in order after BeforeRequest.
+	// Each receives the logger returned by the previous function.
+	// Use this so independent packages can each contribute fields to the request logger.
+	BeforeRequests []func(echo.Context, *slog.Logger) *slog.Logger
+	// Additional AfterRequest functions, called in order after AfterRequest.
+	// Each receives the logger returned by the previous function.
+	// If any function returns nil, the remaining functions are not called
+	// and the request is not logged.
+	AfterRequests []func(echo.Context, *slog.Logger) *slog.Logger
 	// The function that does the actual logging.
 	// By default, it will log at a certain level based on the status code of the response.
 	DoLog func(echo.Context, *slog.Logger)
+	// If greater than zero, coalesce logs for 5xx responses with the same method, route path, and status,
+	// to avoid flooding logs when something like a dependency is down.
+	// The first such response in each window is logged by DoLog as usual.
+	// Further responses within the window are not logged; instead, a single "request_errors_coalesced"
+	// error is logged (with the outer logger) at the end of the window, with the number of responses.
+	ErrorLogWindow time.Duration
+	// The maximum length, in runes, of the request_query and request_uri attributes.
+	// Longer values are truncated with stringutil.Ellipsis, so abusive requests do not bloat logs.
+	// If zero, use DefaultMaxQueryLogLength. If negative, do not truncate.
+	MaxQueryLogLength int
 }
 
+// DefaultMaxQueryLogLength is the default LoggingMiddlwareConfig.MaxQueryLogLength.
+const DefaultMaxQueryLogLength = 2048
+
 func LoggingMiddleware(outerLogger *slog.Logger) echo.MiddlewareFunc {
 	return LoggingMiddlewareWithConfig(outerLogger, LoggingMiddlwareConfig{})
 }
@@ -56,6 +148,36 @@ func LoggingMiddlewareWithConfig(outerLogger *slog.Logger, cfg LoggingMiddlwareC
 	if cfg.DoLog == nil {
 		cfg.DoLog = LoggingMiddlewareDefaultDoLog
 	}
+	if cfg.SensitiveHeaders == nil {
+		cfg.SensitiveHeaders = DefaultSensitiveHeaders
+	}
+	if cfg.MaxQueryLogLength == 0 {
+		cfg.MaxQueryLogLength = DefaultMaxQueryLogLength
+	}
+	truncate := func(s string) string {
+		if cfg.MaxQueryLogLength < 0 {
+			return s
+		}
+		return stringutil.Ellipsis(s, cfg.MaxQueryLogLength)
+	}
+	sensitive := newHeaderSet(cfg.SensitiveHeaders)
+	beforeRequests := cfg.BeforeRequests
+	if cfg.BeforeRequest != nil {
+		beforeRequests = append([]func(echo.Context, *slog.Logger) *slog.Logger{cfg.BeforeRequest}, beforeRequests...)
+	}
+	afterRequests := cfg.AfterRequests
+	if cfg.AfterRequest != nil {
+		afterRequests = append([]func(echo.Context, *slog.Logger) *slog.Logger{cfg.AfterRequest}, afterRequests...)
+	}
+	if cfg.ErrorLogWindow > 0 {
+		coalescer := &errorLogCoalescer{logger: outerLogger, window: cfg.ErrorLogWindow, counts: map[errorLogKey]int{}}
+		doLog := cfg.DoLog
+		cfg.DoLog = func(c echo.Context, logger *slog.Logger) {
+			if !coalescer.Suppress(c) {
+				doLog(c, logger)
+			}
+		}
+	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			start := time.Now()
@@ -73,11 +195,14 @@ func LoggingMiddlewareWithConfig(outerLogger *slog.Logger, cfg LoggingMiddlwareC
 			if !cfg.SkipTraceAttrs {
 				logger = logger.With(string(logctx.RequestTraceIdKey), TraceId(c))
 			}
-			if cfg.BeforeRequest != nil {
-				logger = cfg.BeforeRequest(c, logger)
+			for _, before := range beforeRequests {
+				logger = before(c, logger)
 			}
 
 			SetLogger(c, logger)
+			c.Set(sensitiveHeadersKey, sensitive)
+			spans := &requestSpans{spans: map[string]time.Duration{}}
+			c.Set(requestSpansKey, spans)
 
 			err := safeInvokeNext(logger, next, c)
 			err = adaptToError(err)
@@ -92,11 +217,11 @@ func LoggingMiddlewareWithConfig(outerLogger *slog.Logger, cfg LoggingMiddlwareC
 				"request_started_at", start.Format(time.RFC3339),
 				"request_remote_ip", c.RealIP(),
 				"request_method", req.Method,
-				"request_uri", req.RequestURI,
+				"request_uri", truncate(req.RequestURI),
 				"request_protocol", req.Proto,
 				"request_host", req.Host,
 				"request_path", path,
-				"request_query", req.URL.RawQuery,
+				"request_query", truncate(req.URL.RawQuery),
 				"request_referer", req.Referer(),
 				"request_user_agent", req.UserAgent(),
 				"request_bytes_in", bytesIn,
@@ -107,24 +232,22 @@ func LoggingMiddlewareWithConfig(outerLogger *slog.Logger, cfg LoggingMiddlwareC
 				"request_bytes_out", strconv.FormatInt(res.Size, 10),
 			)
 			if cfg.RequestHeaders {
-				for k, v := range req.Header {
-					if len(v) > 0 && k != "Authorization" && k != "Cookie" {
-						logger = logger.With("request_header."+k, v[0])
-					}
-				}
+				logger = withHeaderGroup(logger, "request_header", req.Header, sensitive)
 			}
 			if cfg.ResponseHeaders {
-				for k, v := range res.Header() {
-					if len(v) > 0 && k != "Set-Cookie" {
-						logger = logger.With("response_header."+k, v[0])
-					}
-				}
+				logger = withHeaderGroup(logger, "response_header", res.Header(), sensitive)
+			}
+			if attr, ok := spans.attr(); ok {
+				logger = logger.With(attr)
 			}
 			if err != nil {
 				logger = logger.With("request_error", err)
 			}
-			if cfg.AfterRequest != nil {
-				logger = cfg.AfterRequest(c, logger)
+			for _, after := range afterRequests {
+				if logger == nil {
+					break
+				}
+				logger = after(c, logger)
 			}
 			if logger != nil {
 				cfg.DoLog(c, logger)
@@ -151,6 +274,62 @@ func LoggingMiddlewareDefaultDoLog(c echo.Context, logger *slog.Logger) {
 	logMethod("request_finished")
 }
 
+type errorLogKey struct {
+	method string
+	path   string
+	status int
+}
+
+// errorLogCoalescer tracks 5xx responses so repeated errors can be summarized.
+// See LoggingMiddlwareConfig.ErrorLogWindow.
+type errorLogCoalescer struct {
+	logger *slog.Logger
+	window time.Duration
+	mux    sync.Mutex
+	// Number of suppressed responses for each key with an active window.
+	counts map[errorLogKey]int
+}
+
+// Suppress returns true if the response should not be logged,
+// because a response with the same key has already been logged in the current window.
+func (co *errorLogCoalescer) Suppress(c echo.Context) bool {
+	status := c.Response().Status
+	if status < 500 {
+		return false
+	}
+	path := c.Path()
+	if path == "" {
+		path = c.Request().URL.Path
+	}
+	key := errorLogKey{method: c.Request().Method, path: path, status: status}
+	co.mux.Lock()
+	defer co.mux.Unlock()
+	if n, ok := co.counts[key]; ok {
+		co.counts[key] = n + 1
+		return true
+	}
+	co.counts[key] = 0
+	time.AfterFunc(co.window, func() { co.flush(key) })
+	return false
+}
+
+func (co *errorLogCoalescer) flush(key errorLogKey) {
+	co.mux.Lock()
+	n := co.counts[key]
+	delete(co.counts, key)
+	co.mux.Unlock()
+	if n == 0 {
+		return
+	}
+	co.logger.With(
+		"request_method", key.method,
+		"request_path", key.path,
+		"request_status", key.status,
+		"request_count", n,
+		"request_window_ms", co.window.Milliseconds(),
+	).Error("request_errors_coalesced")
+}
+
 // Invoke next(c) within a function wrapped with defer,
 // so that if it panics, we can recover from it and pass on a 500.
 // Use the "named return parameter can be set in defer" trick so we can
@@ -185,15 +364,11 @@ func adaptToError(e error) error {
 	}
 	var ee *echo.HTTPError
 	if errors.As(e, &ee) {
-		apiErr := NewError(ee.Code, "echo", ee.Internal)
-		apiErr.Message = fmt.Sprintf("%v", ee.Message)
-		return apiErr
+		return FromEchoError(ee)
 	}
 	var ae apiparams.HTTPError
 	if errors.As(e, &ae) {
-		apiErr := NewError(ae.Code(), "validation", ae)
-		apiErr.Message = ae.Error()
-		return apiErr
+		return FromApiparamsError(ae)
 	}
 	return NewInternalError(e)
 }
@@ -207,6 +382,9 @@ func NewHTTPErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
 		}
 		// This is based on echo's default error handler,
 		if !c.Response().Committed {
+			for k, v := range apiErr.Headers {
+				c.Response().Header().Set(k, v)
+			}
 			// We can have api errors that are using a non-error status code.
 			// We should still return a spec-correct response,
 			// using no body for 204, 304, and HEAD requests.
@@ -217,7 +395,14 @@ func NewHTTPErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
 			if noContent {
 				err = c.NoContent(apiErr.HTTPStatus)
 			} else {
-				err = c.JSON(apiErr.HTTPStatus, apiErr)
+				switch negotiateErrorFormat(c.Request().Header.Get(echo.HeaderAccept)) {
+				case echo.MIMEApplicationXML:
+					err = c.XML(apiErr.HTTPStatus, apiErr)
+				case echo.MIMETextPlain:
+					err = c.String(apiErr.HTTPStatus, apiErr.Error())
+				default:
+					err = c.JSON(apiErr.HTTPStatus, apiErr)
+				}
 			}
 			if err != nil {
 				Logger(c).With("error", err).Error("http_error_handler_error")
@@ -225,3 +410,44 @@ func NewHTTPErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
 		}
 	}
 }
+
+// negotiateErrorFormat returns the MIME type used to render an api.Error,
+// based on the supported media type in the Accept header with the highest quality (q) value.
+// Media types with q=0 are not acceptable. Ties go to the type that comes first in the header.
+// Return application/json if no supported type is found.
+func negotiateErrorFormat(accept string) string {
+	best, bestQ := echo.MIMEApplicationJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		var format string
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case echo.MIMEApplicationJSON, "*/*":
+			format = echo.MIMEApplicationJSON
+		case echo.MIMEApplicationXML, echo.MIMETextXML:
+			format = echo.MIMEApplicationXML
+		case echo.MIMETextPlain:
+			format = echo.MIMETextPlain
+		default:
+			continue
+		}
+		if q := acceptQuality(params); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the q parameter in the parameters of an Accept media range,
+// like "q=0.5" in "text/plain;q=0.5", or 1 if there is no valid q parameter.
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(param, "=")
+		if strings.ToLower(strings.TrimSpace(k)) != "q" {
+			continue
+		}
+		if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+			return q
+		}
+	}
+	return 1
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RequestMetricLabels are the labels MetricsMiddleware records each request with.
+type RequestMetricLabels struct {
+	// Method is the request method, like "GET".
+	Method string
+	// Route is the route pattern that matched the request (c.Path()), like "/users/:id",
+	// so requests for different resources are aggregated together.
+	// It is UnmatchedRoute if no route matched.
+	Route string
+	// StatusClass is the class of the response status, like "2xx" or "5xx".
+	StatusClass string
+}
+
+// UnmatchedRoute is the RequestMetricLabels.Route for requests that do not match a route,
+// so random paths do not each create their own series.
+const UnmatchedRoute = "unmatched"
+
+// Collector receives the metrics recorded by MetricsMiddleware.
+// Implement it to export metrics to your metrics system, like prometheus/client_golang:
+//
+//	func (c promCollector) ObserveRequest(l api.RequestMetricLabels, latency time.Duration) {
+//		c.requests.WithLabelValues(l.Method, l.Route, l.StatusClass).Inc()
+//		c.latency.WithLabelValues(l.Method, l.Route, l.StatusClass).Observe(latency.Seconds())
+//	}
+//
+// ObserveRequest is called concurrently, so must be goroutine-safe.
+type Collector interface {
+	// ObserveRequest records a finished request and how long it took.
+	// Each call should increment the request count for the labels,
+	// and add latency to the latency histogram for the labels.
+	ObserveRequest(labels RequestMetricLabels, latency time.Duration)
+}
+
+type MetricsConfig struct {
+	// Collector receives the metrics for each request. Required.
+	Collector Collector
+	// If provided, requests this returns true for are not recorded,
+	// such as health checks.
+	Skipper func(echo.Context) bool
+}
+
+// MetricsMiddleware records the count and latency of requests to cfg.Collector,
+// labeled by method, route pattern, and status class (see RequestMetricLabels).
+//
+// The status of a request that returns an error is the status it will be rendered with
+// (see NewHTTPErrorHandler), so MetricsMiddleware can be used inside of LoggingMiddleware,
+// like with e.Use after New. Panics are recorded as a 500, and re-panicked.
+func MetricsMiddleware(cfg MetricsConfig) echo.MiddlewareFunc {
+	if cfg.Collector == nil {
+		panic("MetricsConfig.Collector is required")
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+			start := time.Now()
+			completed := false
+			defer func() {
+				status := http.StatusInternalServerError
+				if completed {
+					status = responseStatus(c, err)
+				}
+				route := c.Path()
+				if route == "" {
+					route = UnmatchedRoute
+				}
+				labels := RequestMetricLabels{
+					Method:      c.Request().Method,
+					Route:       route,
+					StatusClass: StatusClass(status),
+				}
+				cfg.Collector.ObserveRequest(labels, time.Since(start))
+			}()
+			err = next(c)
+			completed = true
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status the response has, or will have once err is rendered.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	return adaptToError(err).(Error).HTTPStatus
+}
+
+// StatusClass returns the class of an HTTP status, like "2xx" for 204.
+func StatusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// DefaultLatencyBuckets are the upper bounds of the histogram buckets used by MemoryCollector.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// NewMemoryCollector returns a Collector that keeps metrics in memory,
+// with latency histogram buckets with the given upper bounds.
+// If buckets is empty, use DefaultLatencyBuckets.
+// It is mostly useful for tests.
+func NewMemoryCollector(buckets ...time.Duration) *MemoryCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &MemoryCollector{buckets: buckets, series: make(map[RequestMetricLabels]*memorySeries)}
+}
+
+type MemoryCollector struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	series  map[RequestMetricLabels]*memorySeries
+}
+
+type memorySeries struct {
+	count int
+	// bucketCounts has a count for each bucket, and then for +Inf.
+	// Like Prometheus, each count is non-cumulative here, and made cumulative in BucketCounts.
+	bucketCounts []int
+	sum          time.Duration
+}
+
+var _ Collector = &MemoryCollector{}
+
+func (m *MemoryCollector) ObserveRequest(labels RequestMetricLabels, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[labels]
+	if !ok {
+		s = &memorySeries{bucketCounts: make([]int, len(m.buckets)+1)}
+		m.series[labels] = s
+	}
+	s.count++
+	s.sum += latency
+	i := sort.Search(len(m.buckets), func(i int) bool { return latency <= m.buckets[i] })
+	s.bucketCounts[i]++
+}
+
+// Count returns the number of requests recorded with labels.
+func (m *MemoryCollector) Count(labels RequestMetricLabels) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.series[labels]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// Sum returns the total latency of requests recorded with labels.
+func (m *MemoryCollector) Sum(labels RequestMetricLabels) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.series[labels]; ok {
+		return s.sum
+	}
+	return 0
+}
+
+// Buckets returns the upper bounds of the latency histogram buckets.
+func (m *MemoryCollector) Buckets() []time.Duration {
+	return append([]time.Duration(nil), m.buckets...)
+}
+
+// BucketCounts returns the cumulative count of requests recorded with labels
+// with a latency less than or equal to each bucket in Buckets.
+// There is a final count for +Inf, which is equal to Count.
+func (m *MemoryCollector) BucketCounts(labels RequestMetricLabels) []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]int, len(m.buckets)+1)
+	s, ok := m.series[labels]
+	if !ok {
+		return result
+	}
+	total := 0
+	for i, n := range s.bucketCounts {
+		total += n
+		result[i] = total
+	}
+	return result
+}
+
+// Labels returns the labels of every series recorded, in no particular order.
+func (m *MemoryCollector) Labels() []RequestMetricLabels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]RequestMetricLabels, 0, len(m.series))
+	for l := range m.series {
+		result = append(result, l)
+	}
+	return result
+}
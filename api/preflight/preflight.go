@@ -1,31 +1,84 @@
 package preflight
 
 import (
+	"context"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/lithictech/go-aperitif/v2/api"
+	"github.com/lithictech/go-aperitif/v2/backoff"
 	"github.com/pkg/errors"
 	"time"
 )
 
+// CheckFunc is a preflight check that does not depend on echo,
+// so it can be reused elsewhere, like in startup code.
+// The context is the request's context, so it is cancelled if the request is.
+type CheckFunc func(ctx context.Context) error
+
 type Config struct {
 	// The preflight check to execute.
+	// Prefer CheckContext for new code; if both are set, CheckContext is used.
 	Check echo.HandlerFunc
+	// The preflight check to execute, called with the request context.
+	CheckContext CheckFunc
 	// Preflight checks will never wait longer than this amount of time.
 	MaxTotalWait time.Duration
 	// Retries will never be further than this far apart.
 	MaxRetryWait time.Duration
+	// Fraction of each retry wait to randomize. See backoff.Backoff.
+	// Defaults to 0 (no jitter).
+	RetryJitter float64
+	// Requests for these paths bypass the preflight check entirely.
+	// If nil, defaults to DefaultSkipPaths, so the health and status endpoints
+	// do not depend on whatever the preflight check is checking.
+	// Use an empty (non-nil) slice to skip no paths.
+	//
+	// DefaultSkipPaths only has the default api.HealthPath and api.StatusPath,
+	// since the middleware cannot see the api.Config the app was built with.
+	// If you set api.Config.HealthPath or StatusPath, pass those paths here,
+	// or the custom health endpoint is gated by the preflight check.
+	SkipPaths []string
+	// If Skipper returns true, the request bypasses the preflight check.
+	// Used in addition to SkipPaths.
+	Skipper middleware.Skipper
 }
 
+// DefaultSkipPaths are the paths that bypass preflight checks if Config.SkipPaths is nil.
+// They are the default api.HealthPath and api.StatusPath; see Config.SkipPaths.
+var DefaultSkipPaths = []string{api.HealthPath, api.StatusPath}
+
 func Middleware(check echo.HandlerFunc) echo.MiddlewareFunc {
 	return MiddlewareWithConfig(Config{Check: check})
 }
 
+// ContextMiddleware returns preflight middleware using a context-aware check.
+func ContextMiddleware(check CheckFunc) echo.MiddlewareFunc {
+	return MiddlewareWithConfig(Config{CheckContext: check})
+}
+
 func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
+	if cfg.CheckContext != nil {
+		check := cfg.CheckContext
+		cfg.Check = func(c echo.Context) error {
+			return check(c.Request().Context())
+		}
+	}
 	if cfg.MaxTotalWait == 0 {
 		cfg.MaxTotalWait = time.Second * 30
 	}
 	if cfg.MaxRetryWait == 0 {
 		cfg.MaxRetryWait = time.Second * 2
 	}
+	if cfg.SkipPaths == nil {
+		cfg.SkipPaths = DefaultSkipPaths
+	}
+	if cfg.Skipper == nil {
+		cfg.Skipper = middleware.DefaultSkipper
+	}
+	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skipPaths[p] = true
+	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		if cfg.Check == nil {
 			return func(c echo.Context) error {
@@ -33,6 +86,9 @@ func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
 			}
 		}
 		return func(c echo.Context) error {
+			if skipPaths[c.Request().URL.Path] || cfg.Skipper(c) {
+				return next(c)
+			}
 			// If preflight checks pass, go right on ahead
 			if checkErr := cfg.Check(c); checkErr == nil {
 				return next(c)
@@ -43,9 +99,22 @@ func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
 			// If we ever get nil for a check, keep going.
 			started := time.Now()
 			giveUpAt := started.Add(cfg.MaxTotalWait)
-			retryWait := 50 * time.Millisecond
+			retryWait := &backoff.Backoff{
+				Base:   50 * time.Millisecond,
+				Max:    cfg.MaxRetryWait,
+				Jitter: cfg.RetryJitter,
+			}
+			// Stop waiting if the request is cancelled (like the client going away,
+			// or the server shutting down), so we do not hold up termination.
+			ctx := c.Request().Context()
 			for {
-				time.Sleep(retryWait)
+				timer := time.NewTimer(retryWait.Next())
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return errors.Wrap(ctx.Err(), "preflight checks cancelled")
+				case <-timer.C:
+				}
 				checkErr := cfg.Check(c)
 				if checkErr == nil {
 					return next(c)
@@ -53,10 +122,6 @@ func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
 				if time.Now().After(giveUpAt) {
 					return errors.Wrap(checkErr, "preflight checks failed")
 				}
-				retryWait *= 2
-				if retryWait > cfg.MaxRetryWait {
-					retryWait = cfg.MaxRetryWait
-				}
 			}
 		}
 	}
@@ -1,6 +1,7 @@
 package preflight_test
 
 import (
+	"context"
 	"errors"
 	"github.com/labstack/echo/v4"
 	"github.com/lithictech/go-aperitif/v2/api"
@@ -77,6 +78,21 @@ var _ = Describe("preflight", func() {
 		Expect(rr).To(HaveResponseCode(204))
 		Expect(calls).To(BeEquivalentTo(5))
 	})
+	It("can use a context-aware check", func() {
+		var ctxs []context.Context
+		e.GET("/", noop, preflight.ContextMiddleware(func(ctx context.Context) error {
+			ctxs = append(ctxs, ctx)
+			if len(ctxs) > 1 {
+				return nil
+			}
+			return errors.New("nope")
+		}))
+		req := GetRequest("/")
+		rr := Serve(e, req)
+		Expect(rr).To(HaveResponseCode(204))
+		Expect(ctxs).To(HaveLen(2))
+		Expect(ctxs[0]).To(BeIdenticalTo(req.Context()))
+	})
 	It("errors if the check is not defined", func() {
 		e.GET("/", noop, preflight.Middleware(nil))
 		req := GetRequest("/")
@@ -84,4 +100,86 @@ var _ = Describe("preflight", func() {
 		Expect(rr).To(HaveResponseCode(500))
 		Expect(rr.Body.String()).To(ContainSubstring("preflight check not configured"))
 	})
+	It("stops retrying if the request context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		e.GET("/", noop, preflight.MiddlewareWithConfig(preflight.Config{
+			Check: func(c echo.Context) error {
+				calls++
+				cancel()
+				return errors.New("nope")
+			},
+			MaxRetryWait: time.Second * 10,
+		}))
+		req := GetRequest("/").WithContext(ctx)
+		start := time.Now()
+		rr := Serve(e, req)
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(rr).To(HaveResponseCode(500))
+		Expect(rr.Body.String()).To(ContainSubstring("preflight checks cancelled"))
+		Expect(calls).To(Equal(1))
+	})
+	Describe("skipping", func() {
+		var calls int
+		failing := func(c echo.Context) error {
+			calls++
+			return errors.New("nope")
+		}
+
+		BeforeEach(func() {
+			calls = 0
+		})
+
+		It("skips the health and status paths by default", func() {
+			e.Use(preflight.MiddlewareWithConfig(preflight.Config{Check: failing}))
+			Expect(Serve(e, GetRequest(api.HealthPath))).To(HaveResponseCode(200))
+			Expect(Serve(e, GetRequest(api.StatusPath))).To(HaveResponseCode(200))
+			Expect(calls).To(Equal(0))
+		})
+
+		It("skips only the configured paths if SkipPaths is set", func() {
+			e.GET("/skipped", noop)
+			e.Use(preflight.MiddlewareWithConfig(preflight.Config{
+				Check:        failing,
+				SkipPaths:    []string{"/skipped"},
+				MaxTotalWait: time.Millisecond,
+				MaxRetryWait: time.Millisecond,
+			}))
+			Expect(Serve(e, GetRequest("/skipped"))).To(HaveResponseCode(204))
+			Expect(calls).To(Equal(0))
+			Expect(Serve(e, GetRequest(api.HealthPath))).To(HaveResponseCode(500))
+			Expect(calls).To(BeNumerically(">", 0))
+		})
+
+		It("requires SkipPaths to skip a custom health path", func() {
+			e = api.New(api.Config{HealthPath: "/custom-health"})
+			e.Use(preflight.MiddlewareWithConfig(preflight.Config{
+				Check:        failing,
+				MaxTotalWait: time.Millisecond,
+				MaxRetryWait: time.Millisecond,
+			}))
+			Expect(Serve(e, GetRequest("/custom-health"))).To(HaveResponseCode(500))
+			Expect(calls).To(BeNumerically(">", 0))
+
+			calls = 0
+			e = api.New(api.Config{HealthPath: "/custom-health"})
+			e.Use(preflight.MiddlewareWithConfig(preflight.Config{
+				Check:     failing,
+				SkipPaths: []string{"/custom-health"},
+			}))
+			Expect(Serve(e, GetRequest("/custom-health"))).To(HaveResponseCode(200))
+			Expect(calls).To(Equal(0))
+		})
+
+		It("skips requests where the Skipper returns true", func() {
+			e.GET("/", noop, preflight.MiddlewareWithConfig(preflight.Config{
+				Check: failing,
+				Skipper: func(c echo.Context) bool {
+					return c.Request().Header.Get("X-Skip") != ""
+				},
+			}))
+			Expect(Serve(e, GetRequest("/", SetReqHeader("X-Skip", "1")))).To(HaveResponseCode(204))
+			Expect(calls).To(Equal(0))
+		})
+	})
 })
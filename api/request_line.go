@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// DefaultMaxURILength is the default MaxRequestLineConfig.MaxURILength.
+const DefaultMaxURILength = 8192
+
+// DefaultMaxQueryLength is the default MaxRequestLineConfig.MaxQueryLength.
+const DefaultMaxQueryLength = 4096
+
+type MaxRequestLineConfig struct {
+	// MaxURILength is the maximum length of the request URI (path and query).
+	// If zero, use DefaultMaxURILength.
+	MaxURILength int
+	// MaxQueryLength is the maximum length of the raw query string.
+	// If zero, use DefaultMaxQueryLength.
+	MaxQueryLength int
+}
+
+// MaxRequestLineMiddleware rejects requests with a URI or query string longer than configured,
+// with a 414 (URI Too Long) api.Error with a code of "uri_too_long".
+// Use it to protect against abusive clients sending extremely long URLs.
+// Note that LoggingMiddleware truncates the logged query separately
+// (see LoggingMiddlwareConfig.MaxQueryLogLength).
+func MaxRequestLineMiddleware(cfg MaxRequestLineConfig) echo.MiddlewareFunc {
+	if cfg.MaxURILength == 0 {
+		cfg.MaxURILength = DefaultMaxURILength
+	}
+	if cfg.MaxQueryLength == 0 {
+		cfg.MaxQueryLength = DefaultMaxQueryLength
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			uri := req.RequestURI
+			if uri == "" {
+				// Not set for client requests, like in tests.
+				uri = req.URL.RequestURI()
+			}
+			if len(uri) > cfg.MaxURILength || len(req.URL.RawQuery) > cfg.MaxQueryLength {
+				return NewError(http.StatusRequestURITooLong, "uri_too_long")
+			}
+			return next(c)
+		}
+	}
+}
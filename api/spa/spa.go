@@ -7,6 +7,7 @@ package spa
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"log"
 	"net/http"
 )
@@ -24,6 +25,32 @@ type Config struct {
 	// When Handle returns true, this is the path passed to the Static middleware.
 	// Defaults to index.html.
 	Path string
+	// If Path cannot be served by the Static middleware (usually because the static directory
+	// is misconfigured), this path is passed to the Static middleware instead,
+	// such as a 404.html page explaining the problem.
+	// It is served with a 404 status, so a misconfigured static directory
+	// is not mistaken for a working one.
+	// If empty, or it also cannot be served, the request falls through to the next handler.
+	NotFoundPath string
+	// If true, requests where Handle returns false (like API paths) are never passed to
+	// the Static middleware. A request that does not match a registered route
+	// gets the app's normal (usually JSON) 404, rather than a static file or HTML page.
+	StrictUnhandledPaths bool
+}
+
+// StaticConfig returns an echo Static middleware serving files from root,
+// tuned for use as Config.Static:
+// HTML5 mode is off (the spa middleware handles fallback to index.html),
+// directory browsing is off, and the index file is index.html.
+//
+// Usage: e.Use(spa.Middleware(spa.StaticConfig("public"), matcher))
+func StaticConfig(root string) echo.MiddlewareFunc {
+	return middleware.StaticWithConfig(middleware.StaticConfig{
+		Root:   root,
+		Index:  "index.html",
+		HTML5:  false,
+		Browse: false,
+	})
 }
 
 func Middleware(static echo.MiddlewareFunc, handle Matcher) echo.MiddlewareFunc {
@@ -51,7 +78,7 @@ func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		// Wrap our custom handler in the static middleware,
 		// so we never run our handler if the static middleware matches.
-		return cfg.Static(func(c echo.Context) error {
+		spaHandler := cfg.Static(func(c echo.Context) error {
 			handle, err := cfg.Handle(c.Request())
 			if err != nil {
 				return err
@@ -74,7 +101,46 @@ func MiddlewareWithConfig(cfg Config) echo.MiddlewareFunc {
 			// So, call the static middleware as if we were requesting index.html originally.
 			req.URL.Path = cfg.Path
 			c.SetRequest(req)
-			return cfg.Static(next)(c)
+			return cfg.Static(notFoundHandler(cfg, next))(c)
+		})
+		if !cfg.StrictUnhandledPaths {
+			return spaHandler
+		}
+		return func(c echo.Context) error {
+			handle, err := cfg.Handle(c.Request())
+			if err != nil {
+				return err
+			}
+			if !handle {
+				return next(c)
+			}
+			return spaHandler(c)
+		}
+	}
+}
+
+// notFoundHandler returns the handler called when the Static middleware cannot serve cfg.Path.
+// If there is a NotFoundPath, try to serve it with a 404 status, otherwise call next.
+func notFoundHandler(cfg Config, next echo.HandlerFunc) echo.HandlerFunc {
+	if cfg.NotFoundPath == "" {
+		return next
+	}
+	return func(c echo.Context) error {
+		req := c.Request()
+		req.URL.Path = cfg.NotFoundPath
+		c.SetRequest(req)
+		// The Static middleware writes a 200 when it serves the file,
+		// so change the status right before it is written.
+		// If the file cannot be served, leave the status to next.
+		servingNotFound := true
+		c.Response().Before(func() {
+			if servingNotFound && c.Response().Status == http.StatusOK {
+				c.Response().Status = http.StatusNotFound
+			}
 		})
+		return cfg.Static(func(c echo.Context) error {
+			servingNotFound = false
+			return next(c)
+		})(c)
 	}
 }
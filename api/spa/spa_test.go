@@ -9,6 +9,8 @@ import (
 	. "github.com/onsi/gomega"
 	. "github.com/rgalanakis/golangal"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -85,4 +87,114 @@ var _ = Describe("spa Middleware", func() {
 		rr := Serve(e, req)
 		Expect(rr).To(HaveResponseCode(404))
 	})
+
+	Describe("not found handling", func() {
+		// staticServing returns a Static middleware that serves only the given paths.
+		staticServing := func(paths ...string) echo.MiddlewareFunc {
+			return func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					for _, p := range paths {
+						if c.Request().URL.Path == p {
+							return c.String(200, "served "+p)
+						}
+					}
+					return next(c)
+				}
+			}
+		}
+
+		It("serves the NotFoundPath if the SPA path cannot be served", func() {
+			e.Use(spa.MiddlewareWithConfig(spa.Config{
+				Handle:       skipV1,
+				Static:       staticServing("404.html"),
+				NotFoundPath: "404.html",
+			}))
+			rr := Serve(e, GetRequest("/some-callback"))
+			Expect(rr).To(HaveResponseCode(404))
+			Expect(rr.Body.String()).To(Equal("served 404.html"))
+		})
+
+		It("still serves the SPA path if it can be served", func() {
+			e.Use(spa.MiddlewareWithConfig(spa.Config{
+				Handle:       skipV1,
+				Static:       staticServing("index.html", "404.html"),
+				NotFoundPath: "404.html",
+			}))
+			rr := Serve(e, GetRequest("/some-callback"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(rr.Body.String()).To(Equal("served index.html"))
+		})
+
+		It("falls through if neither the SPA path nor the NotFoundPath can be served", func() {
+			e.Use(spa.MiddlewareWithConfig(spa.Config{
+				Handle:       skipV1,
+				Static:       staticServing(),
+				NotFoundPath: "404.html",
+			}))
+			Expect(Serve(e, GetRequest("/some-callback"))).To(HaveResponseCode(404))
+		})
+
+		It("does not pass unhandled paths to Static if StrictUnhandledPaths is set", func() {
+			e.Use(spa.MiddlewareWithConfig(spa.Config{
+				Handle:               skipV1,
+				Static:               staticServing("/v1/missing", "index.html"),
+				StrictUnhandledPaths: true,
+			}))
+			rr := Serve(e, GetRequest("/v1/missing"))
+			Expect(rr).To(HaveResponseCode(404))
+			Expect(rr).To(HaveJsonBody(HaveKeyWithValue("message", "Not Found")))
+
+			rr = Serve(e, GetRequest("/some-callback"))
+			Expect(rr.Body.String()).To(Equal("served index.html"))
+		})
+	})
+
+	Describe("StaticConfig", func() {
+		var root string
+
+		BeforeEach(func() {
+			root = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(root, "index.html"), []byte("the index"), 0o600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "app.js"), []byte("the js"), 0o600)).To(Succeed())
+			Expect(os.Mkdir(filepath.Join(root, "assets"), 0o700)).To(Succeed())
+		})
+
+		It("serves static files, and index.html for SPA routes", func() {
+			e.Use(spa.Middleware(spa.StaticConfig(root), skipV1))
+			rr := Serve(e, GetRequest("/app.js"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(rr.Body.String()).To(Equal("the js"))
+
+			rr = Serve(e, GetRequest("/some-callback"))
+			Expect(rr).To(HaveResponseCode(200))
+			Expect(rr.Body.String()).To(Equal("the index"))
+		})
+
+		It("does not serve index.html for unhandled paths", func() {
+			e.Use(spa.Middleware(spa.StaticConfig(root), skipV1))
+			Expect(Serve(e, GetRequest("/v1/missing"))).To(HaveResponseCode(404))
+		})
+
+		It("serves the NotFoundPath file with a 404 if index.html is missing", func() {
+			Expect(os.Remove(filepath.Join(root, "index.html"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "404.html"), []byte("misconfigured"), 0o600)).To(Succeed())
+			e.Use(spa.MiddlewareWithConfig(spa.Config{
+				Handle:       skipV1,
+				Static:       spa.StaticConfig(root),
+				NotFoundPath: "404.html",
+			}))
+			rr := Serve(e, GetRequest("/some-callback"))
+			Expect(rr).To(HaveResponseCode(404))
+			Expect(rr.Body.String()).To(Equal("misconfigured"))
+
+			rr = Serve(e, GetRequest("/app.js"))
+			Expect(rr).To(HaveResponseCode(200))
+		})
+
+		It("does not list directories", func() {
+			e.Use(spa.Middleware(spa.StaticConfig(root), skipV1))
+			rr := Serve(e, GetRequest("/assets"))
+			Expect(rr.Body.String()).To(Equal("the index"))
+		})
+	})
 })
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const requestSpansKey = "request-spans"
+
+// requestSpans accumulates the durations of named spans within a request.
+// It is safe for concurrent use, since handlers may time work in goroutines.
+type requestSpans struct {
+	mux   sync.Mutex
+	names []string
+	spans map[string]time.Duration
+}
+
+func (s *requestSpans) add(name string, d time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.spans[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.spans[name] += d
+}
+
+// attr returns a "request_spans" group of span name to milliseconds,
+// in the order spans were first recorded.
+func (s *requestSpans) attr() (slog.Attr, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if len(s.names) == 0 {
+		return slog.Attr{}, false
+	}
+	attrs := make([]any, len(s.names))
+	for i, name := range s.names {
+		attrs[i] = slog.Int64(name, s.spans[name].Milliseconds())
+	}
+	return slog.Group("request_spans", attrs...), true
+}
+
+// TimeSpan starts timing a named span of the request, like "db" or "render",
+// and returns a function that stops it. Usually used like:
+//
+//	defer api.TimeSpan(c, "db")()
+//
+// The LoggingMiddleware logs the duration of each span, in milliseconds,
+// in a "request_spans" group on the request_finished log line.
+// Timing the same name more than once adds to its duration.
+// If the LoggingMiddleware is not being used, this is a noop.
+func TimeSpan(c echo.Context, name string) func() {
+	spans, ok := c.Get(requestSpansKey).(*requestSpans)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		spans.add(name, time.Since(start))
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware gives each request a deadline d from when the middleware runs.
+// The request context (c.Request().Context()) is replaced with one that is cancelled
+// at the deadline, or when the handler returns.
+//
+// If the deadline passes before the handler returns, and the handler has not written a response,
+// return a 503 (Service Unavailable) api.Error with a code of "request_timeout",
+// which is rendered like any other error (see NewHTTPErrorHandler).
+//
+// The handler is run on the request goroutine, not abandoned at the deadline,
+// since the echo.Context cannot be used safely once the middleware returns.
+// So handlers must pass the request context to anything that may be slow
+// (database queries, outbound HTTP calls, etc), which stop at the deadline.
+func TimeoutMiddleware(d time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), d)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			err := next(c)
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || c.Response().Committed {
+				return err
+			}
+			if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return NewError(http.StatusServiceUnavailable, "request_timeout", err)
+			}
+			return err
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/labstack/echo/v4"
 	"github.com/lithictech/go-aperitif/v2/logctx"
+	"net/http"
+	"strings"
 )
 
 const TraceIdHeader = "Trace-Id"
@@ -43,3 +48,71 @@ func TraceId(c echo.Context) string {
 	c.Response().Header().Set(TraceIdHeader, newId)
 	return newId
 }
+
+// TraceParentHeader is the W3C Trace Context header.
+// See https://www.w3.org/TR/trace-context/#traceparent-header
+const TraceParentHeader = "traceparent"
+
+// SetTraceHeaders sets the Trace-Id header on an outbound request
+// to the active trace id in ctx (see logctx.ActiveTraceId),
+// so downstream services use the same trace id.
+// If the trace id is a UUID (or otherwise 32 hex characters once dashes are removed),
+// a traceparent header is also set.
+// Headers that are already set on the request are left alone,
+// and nothing is set if ctx has no trace id.
+func SetTraceHeaders(ctx context.Context, req *http.Request) {
+	key, traceId := logctx.ActiveTraceId(ctx)
+	if key == logctx.MissingTraceIdKey {
+		return
+	}
+	if req.Header.Get(TraceIdHeader) == "" {
+		req.Header.Set(TraceIdHeader, traceId)
+	}
+	if req.Header.Get(TraceParentHeader) == "" {
+		if tp, ok := traceParent(traceId); ok {
+			req.Header.Set(TraceParentHeader, tp)
+		}
+	}
+}
+
+// traceParent returns a traceparent header value for traceId,
+// with a new random parent id, and the sampled flag set.
+// Return false if traceId cannot be used as a W3C trace id.
+func traceParent(traceId string) (string, bool) {
+	tid := strings.ToLower(strings.ReplaceAll(traceId, "-", ""))
+	if len(tid) != 32 || strings.Trim(tid, "0") == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(tid); err != nil {
+		return "", false
+	}
+	parent := make([]byte, 8)
+	_, _ = rand.Read(parent)
+	return "00-" + tid + "-" + hex.EncodeToString(parent) + "-01", true
+}
+
+// NewTraceTransport returns an http.RoundTripper that calls SetTraceHeaders
+// with the context of each request, before passing it to base.
+// If base is nil, http.DefaultTransport is used.
+// For example:
+//
+//	client := &http.Client{Transport: api.NewTraceTransport(nil)}
+//	req, _ := http.NewRequestWithContext(api.StdContext(c), "GET", url, nil)
+//	resp, err := client.Do(req)
+func NewTraceTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return traceTransport{base}
+}
+
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request, so set headers on a copy.
+	req = req.Clone(req.Context())
+	SetTraceHeaders(req.Context(), req)
+	return t.base.RoundTrip(req)
+}
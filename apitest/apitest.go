@@ -7,6 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type RequestOption func(*http.Request)
@@ -21,6 +27,33 @@ func SetReqHeader(key, value string) RequestOption {
 	}
 }
 
+// WithCookie adds a cookie with the given name and value to the request's Cookie header.
+func WithCookie(name, value string) RequestOption {
+	return func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+// WithCookies adds a cookie for each name and value in cookies to the request's Cookie header.
+func WithCookies(cookies map[string]string) RequestOption {
+	return func(r *http.Request) {
+		for k, v := range cookies {
+			r.AddCookie(&http.Cookie{Name: k, Value: v})
+		}
+	}
+}
+
+// ResponseCookie returns the cookie with the given name set (via Set-Cookie) on the response,
+// or nil if there is no such cookie.
+func ResponseCookie(rr *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
 func SetQueryParam(key string, value interface{}) RequestOption {
 	return SetQueryParams(map[string]interface{}{key: value})
 }
@@ -44,6 +77,16 @@ func NewRequest(method, url string, body []byte, opts ...RequestOption) *http.Re
 	return req
 }
 
+// FormRequest returns a request with values encoded as an application/x-www-form-urlencoded body.
+func FormRequest(method, url string, values map[string]string, opts ...RequestOption) *http.Request {
+	form := make(neturl.Values, len(values))
+	for k, v := range values {
+		form.Set(k, v)
+	}
+	opts = append([]RequestOption{SetReqHeader("Content-Type", "application/x-www-form-urlencoded")}, opts...)
+	return NewRequest(method, url, []byte(form.Encode()), opts...)
+}
+
 func GetRequest(url string, opts ...RequestOption) *http.Request {
 	return NewRequest(http.MethodGet, url, nil, opts...)
 }
@@ -68,6 +111,72 @@ func MustUnmarshalFrom(r io.Reader) interface{} {
 	return out
 }
 
+// HasJSONFields returns an error if the JSON response body does not contain
+// each key in expected with an equal value. Keys not in expected are ignored.
+// Keys can be dotted to refer to nested fields, like "user.name" or "items.0.id".
+// Values are compared after a round-trip through JSON,
+// so expected values like int(5) match a decoded float64(5).
+func HasJSONFields(rr *httptest.ResponseRecorder, expected map[string]interface{}) error {
+	var body interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		return fmt.Errorf("response body is not JSON: %w", err)
+	}
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var problems []string
+	for _, k := range keys {
+		want, err := jsonRoundTrip(expected[k])
+		if err != nil {
+			return fmt.Errorf("expected value for %s: %w", k, err)
+		}
+		got, ok := jsonPath(body, k)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing", k))
+		} else if !reflect.DeepEqual(got, want) {
+			problems = append(problems, fmt.Sprintf("%s: expected %#v, got %#v", k, want, got))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("JSON fields did not match:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func jsonRoundTrip(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func jsonPath(v interface{}, path string) (interface{}, bool) {
+	for _, part := range strings.Split(path, ".") {
+		switch tv := v.(type) {
+		case map[string]interface{}:
+			child, ok := tv[part]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(tv) {
+				return nil, false
+			}
+			v = tv[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
 func must(e error) {
 	if e != nil {
 		panic(e)
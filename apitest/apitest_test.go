@@ -0,0 +1,172 @@
+package apitest_test
+
+import (
+	"github.com/labstack/echo/v4"
+	. "github.com/lithictech/go-aperitif/v2/apitest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestApitest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "apitest package Suite")
+}
+
+var _ = Describe("HasJSONFields", func() {
+	var rr *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		rr = httptest.NewRecorder()
+		rr.Header().Set("Content-Type", "application/json")
+		_, _ = rr.WriteString(`{
+			"id": 5,
+			"ratio": 0.5,
+			"name": "x",
+			"user": {"name": "rob", "roles": ["admin"]},
+			"items": [{"id": 1}, {"id": 2, "tags": ["a", "b"]}],
+			"empty": null
+		}`)
+	})
+
+	It("succeeds if the body contains a subset of the fields", func() {
+		Expect(HasJSONFields(rr, map[string]interface{}{"name": "x"})).To(Succeed())
+		Expect(HasJSONFields(rr, map[string]interface{}{})).To(Succeed())
+		Expect(HasJSONFields(rr, map[string]interface{}{"empty": nil})).To(Succeed())
+	})
+
+	It("compares numbers after a JSON round-trip", func() {
+		Expect(HasJSONFields(rr, map[string]interface{}{
+			"id":    5,
+			"ratio": float32(0.5),
+		})).To(Succeed())
+		Expect(HasJSONFields(rr, map[string]interface{}{"id": float64(5)})).To(Succeed())
+		Expect(HasJSONFields(rr, map[string]interface{}{"id": "5"})).ToNot(Succeed())
+	})
+
+	It("compares nested values", func() {
+		Expect(HasJSONFields(rr, map[string]interface{}{
+			"user": map[string]interface{}{"name": "rob", "roles": []string{"admin"}},
+		})).To(Succeed())
+	})
+
+	It("supports dotted keys for nested fields and array indices", func() {
+		Expect(HasJSONFields(rr, map[string]interface{}{
+			"user.name":    "rob",
+			"user.roles.0": "admin",
+			"items.0.id":   1,
+			"items.1.tags": []string{"a", "b"},
+		})).To(Succeed())
+	})
+
+	It("errors for missing keys", func() {
+		for _, k := range []string{"nope", "user.nope", "items.2.id", "items.x.id", "items.-1.id", "name.first"} {
+			err := HasJSONFields(rr, map[string]interface{}{k: 1})
+			Expect(err).To(MatchError(ContainSubstring(k+": missing")), k)
+		}
+	})
+
+	It("errors for mismatched values, describing each problem in key order", func() {
+		err := HasJSONFields(rr, map[string]interface{}{
+			"name":       "y",
+			"items.0.id": 2,
+			"nope":       true,
+		})
+		Expect(err).To(MatchError("JSON fields did not match:\n" +
+			"items.0.id: expected 2, got 1\n" +
+			"name: expected \"y\", got \"x\"\n" +
+			"nope: missing"))
+	})
+
+	It("errors if the body is not JSON", func() {
+		rr := httptest.NewRecorder()
+		_, _ = rr.WriteString("<html></html>")
+		Expect(HasJSONFields(rr, map[string]interface{}{"x": 1})).To(MatchError(HavePrefix("response body is not JSON: ")))
+	})
+
+	It("errors if an expected value cannot be encoded", func() {
+		err := HasJSONFields(rr, map[string]interface{}{"id": make(chan int)})
+		Expect(err).To(MatchError(HavePrefix("expected value for id: ")))
+	})
+})
+
+var _ = Describe("FormRequest", func() {
+	It("encodes values as a form body", func() {
+		req := FormRequest("POST", "/foo", map[string]string{"name": "rob g", "x": "a&b=c"})
+		Expect(req.Method).To(Equal("POST"))
+		Expect(req.URL.Path).To(Equal("/foo"))
+		Expect(req.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+		body, err := io.ReadAll(req.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("name=rob+g&x=a%26b%3Dc"))
+		req = FormRequest("POST", "/foo", map[string]string{"name": "rob g", "x": "a&b=c"})
+		Expect(req.ParseForm()).To(Succeed())
+		Expect(req.PostForm).To(Equal(url.Values{"name": {"rob g"}, "x": {"a&b=c"}}))
+	})
+
+	It("applies other options after setting the content type", func() {
+		req := FormRequest(
+			"PUT",
+			"/foo",
+			nil,
+			SetReqHeader("X-Test", "1"),
+			SetQueryParam("q", 2),
+			SetReqHeader("Content-Type", "application/x-www-form-urlencoded; charset=utf-8"),
+		)
+		Expect(req.Header.Get("X-Test")).To(Equal("1"))
+		Expect(req.URL.RawQuery).To(Equal("q=2"))
+		Expect(req.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded; charset=utf-8"))
+		Expect(req.ContentLength).To(BeEquivalentTo(0))
+	})
+})
+
+var _ = Describe("cookies", func() {
+	var e *echo.Echo
+
+	BeforeEach(func() {
+		e = echo.New()
+		e.GET("/", func(c echo.Context) error {
+			names := []string{}
+			for _, ck := range c.Cookies() {
+				names = append(names, ck.Name+"="+ck.Value)
+				c.SetCookie(&http.Cookie{Name: "echo_" + ck.Name, Value: ck.Value, Path: "/", HttpOnly: true})
+			}
+			return c.JSON(200, names)
+		})
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		e.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("sends cookies on the request and reads them from the response", func() {
+		rr := serve(GetRequest("/", WithCookie("session", "abc")))
+		Expect(rr.Code).To(Equal(200))
+		Expect(rr.Body.String()).To(MatchJSON(`["session=abc"]`))
+		ck := ResponseCookie(rr, "echo_session")
+		Expect(ck).ToNot(BeNil())
+		Expect(ck.Value).To(Equal("abc"))
+		Expect(ck.Path).To(Equal("/"))
+		Expect(ck.HttpOnly).To(BeTrue())
+	})
+
+	It("can send several cookies", func() {
+		rr := serve(GetRequest("/", WithCookies(map[string]string{"a": "1", "b": "2"}), WithCookie("c", "3")))
+		Expect(MustUnmarshalFrom(rr.Body)).To(ConsistOf("a=1", "b=2", "c=3"))
+		Expect(ResponseCookie(rr, "echo_a").Value).To(Equal("1"))
+		Expect(ResponseCookie(rr, "echo_b").Value).To(Equal("2"))
+		Expect(ResponseCookie(rr, "echo_c").Value).To(Equal("3"))
+	})
+
+	It("returns nil if the response does not set the cookie", func() {
+		rr := serve(GetRequest("/", WithCookie("session", "abc")))
+		Expect(ResponseCookie(rr, "session")).To(BeNil())
+		Expect(ResponseCookie(serve(GetRequest("/")), "echo_session")).To(BeNil())
+	})
+})
@@ -0,0 +1,76 @@
+/*
+Package backoff calculates exponentially increasing wait times,
+with optional jitter, for retry loops.
+
+	b := &backoff.Backoff{Base: 50 * time.Millisecond, Max: 2 * time.Second}
+	for {
+		if err := try(); err == nil {
+			break
+		}
+		time.Sleep(b.Next())
+	}
+*/
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultBase is the first duration returned from Next if Backoff.Base is not set.
+const DefaultBase = 100 * time.Millisecond
+
+// Backoff calculates successive wait durations.
+// It is not safe for concurrent use; use a new Backoff for each retry loop.
+type Backoff struct {
+	// Base is the first duration returned from Next.
+	// Defaults to DefaultBase, so a zero Backoff does not retry without waiting.
+	Base time.Duration
+	// Max caps the duration returned from Next. If 0, there is no cap.
+	Max time.Duration
+	// Multiplier is applied to the previous duration to get the next one.
+	// Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction (from 0 to 1) of each duration that is randomized.
+	// For example, with Jitter of 0.25, a 100ms wait will be between 75ms and 100ms.
+	// Jitter never pushes a duration above Max.
+	// If 0, Next is deterministic.
+	Jitter float64
+	// Rand returns a number in [0, 1) used for jitter. Defaults to rand.Float64.
+	Rand func() float64
+
+	current time.Duration
+}
+
+// Next returns the next duration to wait.
+func (b *Backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.Base
+		if b.current <= 0 {
+			b.current = DefaultBase
+		}
+	} else {
+		mult := b.Multiplier
+		if mult == 0 {
+			mult = 2
+		}
+		b.current = time.Duration(float64(b.current) * mult)
+	}
+	if b.Max > 0 && b.current > b.Max {
+		b.current = b.Max
+	}
+	d := b.current
+	if b.Jitter > 0 {
+		rnd := b.Rand
+		if rnd == nil {
+			rnd = rand.Float64
+		}
+		d -= time.Duration(float64(d) * b.Jitter * rnd())
+	}
+	return d
+}
+
+// Reset starts the backoff over, so the next call to Next returns Base.
+func (b *Backoff) Reset() {
+	b.current = 0
+}
@@ -0,0 +1,64 @@
+package backoff_test
+
+import (
+	"github.com/lithictech/go-aperitif/v2/backoff"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "backoff package Suite")
+}
+
+var _ = Describe("Backoff", func() {
+	It("multiplies each duration and caps at the max", func() {
+		b := &backoff.Backoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+		Expect(b.Next()).To(Equal(10 * time.Millisecond))
+		Expect(b.Next()).To(Equal(20 * time.Millisecond))
+		Expect(b.Next()).To(Equal(40 * time.Millisecond))
+		Expect(b.Next()).To(Equal(50 * time.Millisecond))
+		Expect(b.Next()).To(Equal(50 * time.Millisecond))
+	})
+	It("can use a custom multiplier", func() {
+		b := &backoff.Backoff{Base: 10 * time.Millisecond, Multiplier: 3}
+		Expect(b.Next()).To(Equal(10 * time.Millisecond))
+		Expect(b.Next()).To(Equal(30 * time.Millisecond))
+		Expect(b.Next()).To(Equal(90 * time.Millisecond))
+	})
+	It("uses a default base", func() {
+		b := &backoff.Backoff{}
+		Expect(b.Next()).To(Equal(backoff.DefaultBase))
+		Expect(b.Next()).To(Equal(2 * backoff.DefaultBase))
+		b = &backoff.Backoff{Base: -time.Second, Max: 50 * time.Millisecond}
+		Expect(b.Next()).To(Equal(50 * time.Millisecond))
+	})
+	It("can be reset", func() {
+		b := &backoff.Backoff{Base: 10 * time.Millisecond}
+		b.Next()
+		b.Next()
+		b.Reset()
+		Expect(b.Next()).To(Equal(10 * time.Millisecond))
+	})
+	It("subtracts jitter without exceeding the max", func() {
+		b := &backoff.Backoff{
+			Base:   100 * time.Millisecond,
+			Max:    100 * time.Millisecond,
+			Jitter: 0.5,
+			Rand:   func() float64 { return 0.5 },
+		}
+		Expect(b.Next()).To(Equal(75 * time.Millisecond))
+		Expect(b.Next()).To(Equal(75 * time.Millisecond))
+	})
+	It("keeps jittered durations within range", func() {
+		b := &backoff.Backoff{Base: 100 * time.Millisecond, Max: 100 * time.Millisecond, Jitter: 0.25}
+		for i := 0; i < 100; i++ {
+			Expect(b.Next()).To(And(
+				BeNumerically(">", 75*time.Millisecond),
+				BeNumerically("<=", 100*time.Millisecond),
+			))
+		}
+	})
+})
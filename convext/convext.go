@@ -3,8 +3,10 @@ package convext
 
 import (
 	"encoding/json"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 func Must(e error) {
@@ -27,6 +29,128 @@ func ToObject(o interface{}) (map[string]interface{}, error) {
 	return result, err
 }
 
+// ToObjectNonNil is like ToObject, but nil map and slice fields of o
+// (which JSON encodes as null) are empty maps and slices in the result,
+// so consumers like log fields do not need to check for nil.
+// Nested values are normalized too: fields of nested and embedded structs,
+// elements of slices and arrays, and values of maps with string keys.
+// Values of types that implement json.Marshaler are left as they are encoded.
+// If o is nil, return an empty map.
+func ToObjectNonNil(o interface{}) (map[string]interface{}, error) {
+	m, err := ToObject(o)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return map[string]interface{}{}, nil
+	}
+	fillNilCollections(reflect.ValueOf(o), m)
+	return m, nil
+}
+
+var typeOfJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// fillNilCollections returns the JSON-decoded value current of v,
+// with nulls that came from nil maps and slices in v replaced with empty ones.
+// Maps and slices in current are modified in place.
+func fillNilCollections(v reflect.Value, current interface{}) interface{} {
+	if !v.IsValid() {
+		return current
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return current
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(typeOfJSONMarshaler) || reflect.PointerTo(v.Type()).Implements(typeOfJSONMarshaler) {
+		return current
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if m, ok := current.(map[string]interface{}); ok {
+			fillNilStructFields(v, m)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			if current == nil {
+				return map[string]interface{}{}
+			}
+			return current
+		}
+		m, ok := current.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return current
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if elem, present := m[k]; present {
+				m[k] = fillNilCollections(iter.Value(), elem)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			// A nil []byte is encoded as null, but a non-nil one as a string, so leave it alone.
+			if current == nil && v.Type().Elem().Kind() != reflect.Uint8 {
+				return []interface{}{}
+			}
+			return current
+		}
+		arr, ok := current.([]interface{})
+		if !ok || len(arr) != v.Len() {
+			return current
+		}
+		for i := range arr {
+			arr[i] = fillNilCollections(v.Index(i), arr[i])
+		}
+	}
+	return current
+}
+
+// fillNilStructFields calls fillNilCollections for each field of the struct v
+// in its JSON object m, using the field names encoding/json would use.
+func fillNilStructFields(v reflect.Value, m map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// encoding/json promotes the fields of embedded structs.
+				for fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						break
+					}
+					fv = fv.Elem()
+				}
+				if fv.Kind() == reflect.Struct {
+					fillNilStructFields(fv, m)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if current, present := m[name]; present {
+			m[name] = fillNilCollections(fv, current)
+		}
+	}
+}
+
 func MustToObject(o interface{}) map[string]interface{} {
 	m, err := ToObject(o)
 	Must(err)
@@ -74,3 +198,50 @@ func SortedObjectKeys(o map[string]interface{}) []string {
 	sort.Strings(result)
 	return result
 }
+
+// Coalesce returns the first value in vals that is not the zero value for T,
+// or the zero value if all of them are.
+// Useful for layering config, like Coalesce(fromEnv, fromFile, defaultValue).
+func Coalesce[T comparable](vals ...T) T {
+	var zero T
+	for _, v := range vals {
+		if v != zero {
+			return v
+		}
+	}
+	return zero
+}
+
+// FirstNonEmpty returns the first non-empty string in vals, or "" if all are empty.
+func FirstNonEmpty(vals ...string) string {
+	return Coalesce(vals...)
+}
+
+// MergeMaps returns a new map with the keys of override recursively merged into base.
+// If a key is a map in both base and override, the two maps are merged key-by-key.
+// Otherwise, the value from override wins; this includes slices,
+// which are replaced rather than appended.
+// Neither base nor override is modified; nested maps are copied,
+// but other values (like slices) are shared with the inputs.
+func MergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = MergeMaps(m, nil)
+		}
+		result[k] = v
+	}
+	for k, v := range override {
+		overrideMap, overrideIsMap := v.(map[string]interface{})
+		if !overrideIsMap {
+			result[k] = v
+			continue
+		}
+		if baseMap, baseIsMap := result[k].(map[string]interface{}); baseIsMap {
+			result[k] = MergeMaps(baseMap, overrideMap)
+		} else {
+			result[k] = MergeMaps(overrideMap, nil)
+		}
+	}
+	return result
+}
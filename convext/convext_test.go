@@ -0,0 +1,290 @@
+package convext_test
+
+import (
+	"encoding/json"
+	"github.com/lithictech/go-aperitif/v2/convext"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestConvext(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "convext package Suite")
+}
+
+type marshaled struct {
+	M map[string]int
+}
+
+func (marshaled) MarshalJSON() ([]byte, error) {
+	return []byte(`{"M":null}`), nil
+}
+
+type Tags []string
+
+type Embedded struct {
+	EM map[string]int
+}
+
+type PtrEmbedded struct {
+	PES []int
+}
+
+type item struct {
+	ID   int      `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+var _ = Describe("convext.ToObjectNonNil", func() {
+	It("returns an empty map for nil", func() {
+		Expect(convext.ToObjectNonNil(nil)).To(Equal(map[string]interface{}{}))
+		var p *item
+		Expect(convext.ToObjectNonNil(p)).To(Equal(map[string]interface{}{}))
+	})
+
+	It("replaces nil maps and slices with empty ones", func() {
+		type t struct {
+			M  map[string]int
+			S  []string
+			P  *int
+			I  interface{}
+			FM map[string]int
+			FS []string
+		}
+		m, err := convext.ToObjectNonNil(t{FM: map[string]int{"a": 1}, FS: []string{"x"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"M":  map[string]interface{}{},
+			"S":  []interface{}{},
+			"P":  nil,
+			"I":  nil,
+			"FM": map[string]interface{}{"a": float64(1)},
+			"FS": []interface{}{"x"},
+		}))
+	})
+
+	It("uses JSON field names and skips ignored fields", func() {
+		type t struct {
+			Renamed map[string]int `json:"renamed"`
+			Omitted []int          `json:"omitted,omitempty"`
+			Ignored []int          `json:"-"`
+			Dash    []int          `json:"-,"`
+			private []int
+		}
+		m, err := convext.ToObjectNonNil(&t{private: nil})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"renamed": map[string]interface{}{},
+			"-":       []interface{}{},
+		}))
+	})
+
+	It("normalizes embedded and pointer-embedded structs", func() {
+		type t struct {
+			Embedded
+			*PtrEmbedded
+			Tags
+		}
+		m, err := convext.ToObjectNonNil(t{PtrEmbedded: &PtrEmbedded{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"EM":   map[string]interface{}{},
+			"PES":  []interface{}{},
+			"Tags": []interface{}{},
+		}))
+
+		m, err = convext.ToObjectNonNil(t{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(HaveKeyWithValue("EM", map[string]interface{}{}))
+		Expect(m).ToNot(HaveKey("PES"))
+	})
+
+	It("leaves nil byte slices as null", func() {
+		type t struct {
+			B  []byte
+			FB []byte
+		}
+		m, err := convext.ToObjectNonNil(t{FB: []byte("hi")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{"B": nil, "FB": "aGk="}))
+	})
+
+	It("leaves json.Marshaler values as they are encoded", func() {
+		type t struct {
+			V  marshaled
+			PV *marshaled
+		}
+		m, err := convext.ToObjectNonNil(t{PV: &marshaled{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"V":  map[string]interface{}{"M": nil},
+			"PV": map[string]interface{}{"M": nil},
+		}))
+	})
+
+	It("normalizes nested structs, slices of structs, and map values", func() {
+		type nested struct {
+			Inner *item
+		}
+		type t struct {
+			Nested   nested
+			Items    []item
+			PtrItems []*item
+			ByName   map[string]item
+			Array    [1]item
+		}
+		m, err := convext.ToObjectNonNil(t{
+			Nested:   nested{Inner: &item{ID: 1}},
+			Items:    []item{{ID: 2}, {ID: 3, Tags: []string{"a"}}},
+			PtrItems: []*item{{ID: 4}, nil},
+			ByName:   map[string]item{"x": {ID: 5}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"Nested": map[string]interface{}{
+				"Inner": map[string]interface{}{"id": float64(1), "tags": []interface{}{}},
+			},
+			"Items": []interface{}{
+				map[string]interface{}{"id": float64(2), "tags": []interface{}{}},
+				map[string]interface{}{"id": float64(3), "tags": []interface{}{"a"}},
+			},
+			"PtrItems": []interface{}{
+				map[string]interface{}{"id": float64(4), "tags": []interface{}{}},
+				nil,
+			},
+			"ByName": map[string]interface{}{
+				"x": map[string]interface{}{"id": float64(5), "tags": []interface{}{}},
+			},
+			"Array": []interface{}{
+				map[string]interface{}{"id": float64(0), "tags": []interface{}{}},
+			},
+		}))
+	})
+
+	It("returns marshaling errors", func() {
+		_, err := convext.ToObjectNonNil(map[string]interface{}{"c": make(chan int)})
+		Expect(err).To(BeAssignableToTypeOf(&json.UnsupportedTypeError{}))
+	})
+})
+
+var _ = Describe("convext.MergeMaps", func() {
+	It("merges nested maps recursively", func() {
+		base := map[string]interface{}{
+			"a": 1,
+			"nested": map[string]interface{}{
+				"x":    1,
+				"y":    2,
+				"deep": map[string]interface{}{"p": 1},
+			},
+		}
+		override := map[string]interface{}{
+			"b": 2,
+			"nested": map[string]interface{}{
+				"y":    20,
+				"deep": map[string]interface{}{"q": 2},
+			},
+		}
+		Expect(convext.MergeMaps(base, override)).To(Equal(map[string]interface{}{
+			"a": 1,
+			"b": 2,
+			"nested": map[string]interface{}{
+				"x":    1,
+				"y":    20,
+				"deep": map[string]interface{}{"p": 1, "q": 2},
+			},
+		}))
+	})
+
+	It("replaces slices and scalars with the override value", func() {
+		base := map[string]interface{}{
+			"s":     []interface{}{1, 2},
+			"n":     1,
+			"toMap": "str",
+			"toStr": map[string]interface{}{"x": 1},
+		}
+		override := map[string]interface{}{
+			"s":     []interface{}{3},
+			"n":     nil,
+			"toMap": map[string]interface{}{"x": 2},
+			"toStr": "str",
+		}
+		Expect(convext.MergeMaps(base, override)).To(Equal(map[string]interface{}{
+			"s":     []interface{}{3},
+			"n":     nil,
+			"toMap": map[string]interface{}{"x": 2},
+			"toStr": "str",
+		}))
+	})
+
+	It("handles nil maps", func() {
+		Expect(convext.MergeMaps(nil, nil)).To(Equal(map[string]interface{}{}))
+		Expect(convext.MergeMaps(map[string]interface{}{"a": 1}, nil)).To(Equal(map[string]interface{}{"a": 1}))
+		Expect(convext.MergeMaps(nil, map[string]interface{}{"a": 1})).To(Equal(map[string]interface{}{"a": 1}))
+	})
+
+	It("does not modify either input, including nested maps", func() {
+		base := map[string]interface{}{
+			"nested": map[string]interface{}{"x": 1},
+			"only":   map[string]interface{}{"b": 1},
+		}
+		override := map[string]interface{}{
+			"nested": map[string]interface{}{"y": 2},
+			"added":  map[string]interface{}{"o": 1},
+		}
+		result := convext.MergeMaps(base, override)
+		result["nested"].(map[string]interface{})["z"] = 3
+		result["only"].(map[string]interface{})["c"] = 3
+		result["added"].(map[string]interface{})["p"] = 3
+		result["new"] = 1
+
+		Expect(base).To(Equal(map[string]interface{}{
+			"nested": map[string]interface{}{"x": 1},
+			"only":   map[string]interface{}{"b": 1},
+		}))
+		Expect(override).To(Equal(map[string]interface{}{
+			"nested": map[string]interface{}{"y": 2},
+			"added":  map[string]interface{}{"o": 1},
+		}))
+	})
+})
+
+var _ = Describe("convext.Coalesce and FirstNonEmpty", func() {
+	DescribeTable("Coalesce with strings",
+		func(vals []string, expected string) {
+			Expect(convext.Coalesce(vals...)).To(Equal(expected))
+		},
+		Entry("first non-zero", []string{"", "a", "b"}, "a"),
+		Entry("first value", []string{"a", "", "b"}, "a"),
+		Entry("all zero", []string{"", ""}, ""),
+		Entry("no arguments", nil, ""),
+	)
+
+	DescribeTable("Coalesce with ints",
+		func(vals []int, expected int) {
+			Expect(convext.Coalesce(vals...)).To(Equal(expected))
+		},
+		Entry("first non-zero", []int{0, 0, 3, 4}, 3),
+		Entry("negative values are not zero", []int{0, -1}, -1),
+		Entry("all zero", []int{0, 0}, 0),
+		Entry("no arguments", nil, 0),
+	)
+
+	It("works with pointers and structs", func() {
+		type pair struct{ A, B int }
+		x := 1
+		Expect(convext.Coalesce(nil, &x)).To(Equal(&x))
+		Expect(convext.Coalesce[*int]()).To(BeNil())
+		Expect(convext.Coalesce(pair{}, pair{B: 2}, pair{A: 1})).To(Equal(pair{B: 2}))
+	})
+
+	DescribeTable("FirstNonEmpty",
+		func(vals []string, expected string) {
+			Expect(convext.FirstNonEmpty(vals...)).To(Equal(expected))
+		},
+		Entry("first non-empty", []string{"", "", "c"}, "c"),
+		Entry("whitespace is not empty", []string{"", " ", "c"}, " "),
+		Entry("all empty", []string{"", ""}, ""),
+		Entry("no arguments", nil, ""),
+	)
+})
@@ -19,10 +19,19 @@ func TMin(t, u time.Time) time.Time {
 	return u
 }
 
+// TClamp returns t bounded to the range [min, max].
+// If t is before min, return min; if t is after max, return max.
+// If max is before min, the result is min.
+func TClamp(t, min, max time.Time) time.Time {
+	return TMax(min, TMin(t, max))
+}
+
 // Between returns a slice of Times between the given start and end at each interval.
 // start and end are inclusive.
 // If end is before start, nil is returned.
 // If end is earlier than an interval from start, a slice containing only start is returned.
+// If interval is not positive, a slice containing only start is returned
+// (rather than looping forever).
 func Between(start, end time.Time, interval time.Duration) []time.Time {
 	// Pre-allocate the slice. We should be able to exactly know how big the slice needs to be by
 	// dividing the total distance by the interval/step distance.
@@ -36,16 +45,62 @@ func Between(start, end time.Time, interval time.Duration) []time.Time {
 // BetweenEach calls each for every time between start and end.
 // See Between for more information.
 func BetweenEach(start, end time.Time, interval time.Duration, each func(time.Time)) {
+	if interval <= 0 {
+		if !start.After(end) {
+			each(start)
+		}
+		return
+	}
 	for t := start; !t.After(end); t = t.Add(interval) {
 		each(t)
 	}
 }
 
+// TimeRange is a half-open range of time, [Start, End).
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the range.
+func (r TimeRange) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Contains returns true if t is at or after Start, and before End.
+func (r TimeRange) Contains(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// IntervalsBetween returns consecutive half-open ranges of the given interval,
+// from start until end, like for bucketing time series.
+// The first range starts at start, and each range starts where the previous one ended.
+// If end is not on an interval boundary, the final range is partial: its End is clamped to end.
+// If end is not after start, nil is returned (there is nothing to cover).
+// If interval is not positive, a single range [start, end) is returned
+// (rather than looping forever).
+func IntervalsBetween(start, end time.Time, interval time.Duration) []TimeRange {
+	if !end.After(start) {
+		return nil
+	}
+	if interval <= 0 {
+		return []TimeRange{{Start: start, End: end}}
+	}
+	result := make([]TimeRange, 0, (end.Sub(start)+interval-1)/interval)
+	for t := start; t.Before(end); t = t.Add(interval) {
+		result = append(result, TimeRange{Start: t, End: TMin(t.Add(interval), end)})
+	}
+	return result
+}
+
 // BetweenDates returns a slice of Times between the given start and end dates,
 // adding the given years/months/days between each iteration.
 // start and end are inclusive.
 // If end is before start, nil is returned.
 // If end is earlier than an interval after start, a slice containing only start is returned.
+// If a step does not move forward in time (like offsets all being 0,
+// or mixed-sign offsets that land on or before the previous time),
+// iteration stops there, so the slice ends with that time (rather than looping forever).
 func BetweenDates(start, end time.Time, offsetYear, offsetMonth, offsetDay int) []time.Time {
 	// Pre-allocate a slice by dividing the the total time/distance in days
 	// by the step in days, and adding 1 for the start date.
@@ -65,8 +120,15 @@ func BetweenDates(start, end time.Time, offsetYear, offsetMonth, offsetDay int)
 // BetweenDatesEach calls each for every time between start and end.
 // See BetweenDates for more information.
 func BetweenDatesEach(start, end time.Time, y, m, d int, each func(time.Time)) {
-	for t := start; !t.After(end); t = t.AddDate(y, m, d) {
+	for t := start; !t.After(end); {
 		each(t)
+		// Offsets with mixed signs can move forward on one step and backward on another,
+		// depending on the length of the month, so check every step.
+		next := t.AddDate(y, m, d)
+		if !next.After(t) {
+			return
+		}
+		t = next
 	}
 }
 
@@ -85,6 +147,10 @@ func makeTimeSlice(totalDuration, stepDuration int) []time.Time {
 	if totalDuration < 0 {
 		return make([]time.Time, 0)
 	}
+	// Steps that do not advance only ever include start.
+	if stepDuration <= 0 {
+		return make([]time.Time, 0, 1)
+	}
 	size := totalDuration / stepDuration
 	// 0 size will need 1 entry for the start, since start is inclusive.
 	size++
@@ -98,6 +164,46 @@ func DaysInMonth(t time.Time) int {
 	return lastOfThisMonth.Day()
 }
 
+// StartOfDay returns the first instant of the day of t, in t's location.
+// This is usually midnight, but in time zones where a DST transition skips midnight,
+// it is the transition (like 01:00).
+func StartOfDay(t time.Time) time.Time {
+	return startOfDate(t.Year(), t.Month(), t.Day(), t.Location())
+}
+
+// EndOfDay returns the last instant (nanosecond) of the day of t, in t's location.
+// It is the nanosecond before the next day starts, so days with DST transitions
+// are longer or shorter than 24 hours, as they should be.
+func EndOfDay(t time.Time) time.Time {
+	return startOfDate(t.Year(), t.Month(), t.Day()+1, t.Location()).Add(-time.Nanosecond)
+}
+
+// StartOfMonth returns the first instant of the month of t, in t's location.
+// See StartOfDay.
+func StartOfMonth(t time.Time) time.Time {
+	return startOfDate(t.Year(), t.Month(), 1, t.Location())
+}
+
+// EndOfMonth returns the last instant (nanosecond) of the month of t, in t's location.
+// See EndOfDay.
+func EndOfMonth(t time.Time) time.Time {
+	return EndOfDay(time.Date(t.Year(), t.Month(), DaysInMonth(t), 12, 0, 0, 0, t.Location()))
+}
+
+// startOfDate returns the first instant of the given date (which is normalized like time.Date).
+// If midnight does not exist, because DST starts at midnight,
+// time.Date may return a time on the previous day;
+// the day then starts when that time's zone ends.
+func startOfDate(y int, m time.Month, d int, loc *time.Location) time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	norm := time.Date(y, m, d, 12, 0, 0, 0, loc)
+	if t.Day() != norm.Day() {
+		_, end := t.ZoneBounds()
+		t = end
+	}
+	return t
+}
+
 // RollMonth adds months number of months to t (months can be negative).
 // Unlike Go's time.AddDate, this works on a calendar basis.
 // For example, (October 31).AddDate(0, 1, 0) with Go's time package returns (December 1).
@@ -122,12 +228,24 @@ func RollMonth(t time.Time, months int) time.Time {
 //	offsetMonth(January, 1) => February
 //	offsetMonth(January, 13) => February
 //	offsetMonth(January, -1) => December
+//	offsetMonth(January, -13) => December
 func offsetMonth(m time.Month, offset int) time.Month {
 	zeroBased := int(m - 1)
-	offsetMonth := zeroBased + offset
-	if offsetMonth < 0 {
-		offsetMonth = 12 + offsetMonth
+	mod := (zeroBased + offset) % 12
+	if mod < 0 {
+		mod += 12
 	}
-	mod := offsetMonth % 12
 	return time.Month(1 + mod)
 }
+
+// Quarter returns the calendar quarter of t, from 1 (January through March) to 4.
+func Quarter(t time.Time) int {
+	return (int(t.Month())-1)/3 + 1
+}
+
+// RollQuarter adds quarters number of quarters (3 months) to t (quarters can be negative).
+// Like RollMonth, this works on a calendar basis,
+// so RollQuarter(March 31, 1) returns (June 30).
+func RollQuarter(t time.Time, quarters int) time.Time {
+	return RollMonth(t, quarters*3)
+}
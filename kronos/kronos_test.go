@@ -6,19 +6,59 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"math/rand"
+	"testing"
 	"time"
+	_ "time/tzdata"
 )
 
-var _ = Describe("kronos.TMin/TMax", func() {
-	Describe("TMin", func() {
-		t1 := time.Now()
-		t2 := t1.Add(time.Hour)
+func TestKronos(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "kronos package Suite")
+}
+
+var _ = Describe("kronos.TMin/TMax/TClamp", func() {
+	t1 := time.Now()
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
 
+	Describe("TMin", func() {
 		It("selects the lesser date", func() {
 			Expect(kronos.TMin(t1, t2)).To(BeIdenticalTo(t1))
+			Expect(kronos.TMin(t2, t1)).To(BeIdenticalTo(t1))
+		})
+		It("selects the second date when they are equal", func() {
+			t1utc := t1.UTC()
+			Expect(kronos.TMin(t1, t1utc)).To(BeIdenticalTo(t1utc))
 		})
+	})
+
+	Describe("TMax", func() {
 		It("selects the greater date", func() {
-			Expect(kronos.TMin(t1, t2)).To(BeIdenticalTo(t2))
+			Expect(kronos.TMax(t1, t2)).To(BeIdenticalTo(t2))
+			Expect(kronos.TMax(t2, t1)).To(BeIdenticalTo(t2))
+		})
+		It("selects the second date when they are equal", func() {
+			t1utc := t1.UTC()
+			Expect(kronos.TMax(t1, t1utc)).To(BeIdenticalTo(t1utc))
+		})
+	})
+
+	Describe("TClamp", func() {
+		It("returns t if it is within the range", func() {
+			Expect(kronos.TClamp(t2, t1, t3)).To(BeIdenticalTo(t2))
+		})
+		It("returns min if t is before it", func() {
+			Expect(kronos.TClamp(t1, t2, t3)).To(BeIdenticalTo(t2))
+		})
+		It("returns max if t is after it", func() {
+			Expect(kronos.TClamp(t3, t1, t2)).To(BeIdenticalTo(t2))
+		})
+		It("includes the boundaries", func() {
+			Expect(kronos.TClamp(t1, t1, t3)).To(BeTemporally("==", t1))
+			Expect(kronos.TClamp(t3, t1, t3)).To(BeTemporally("==", t3))
+		})
+		It("returns min if max is before min", func() {
+			Expect(kronos.TClamp(t2, t3, t1)).To(BeIdenticalTo(t3))
 		})
 	})
 })
@@ -51,6 +91,12 @@ var _ = Describe("kronos.Between", func() {
 		Expect(kronos.Between(start, start.Add(59*time.Minute), time.Hour)).To(Equal([]time.Time{start}))
 	})
 
+	It("contains only start if the interval is not positive", func() {
+		start := time.Now()
+		Expect(kronos.Between(start, start.Add(time.Hour), 0)).To(Equal([]time.Time{start}))
+		Expect(kronos.Between(start, start.Add(time.Hour), -time.Minute)).To(Equal([]time.Time{start}))
+	})
+
 	It("is empty if end is after start", func() {
 		start := time.Now()
 		Expect(kronos.Between(start, start.Add(-20*time.Hour), time.Hour)).To(BeEmpty())
@@ -98,6 +144,59 @@ var _ = Describe("kronos.RollMonths", func() {
 		entry(date(2016, 3, 31), -2, date(2016, 1, 31), "through feb with fewer days"),
 		entry(date(2016, 3, 31), -3, date(2015, 12, 31), "over year boundary"),
 		entry(date(2016, 3, 31), -12, date(2015, 3, 31), "back 12 months"),
+		entry(date(2016, 3, 31), -13, date(2015, 2, 28), "back over a year into a shorter month"),
+		entry(date(2016, 1, 15), -25, date(2013, 12, 15), "back over several years"),
+	)
+})
+
+var _ = Describe("kronos.Quarter", func() {
+	DescribeTable("returns the calendar quarter",
+		func(m, expected int) {
+			t := time.Date(2016, time.Month(m), rand.Intn(28)+1, 0, 0, 0, 0, time.UTC)
+			Expect(kronos.Quarter(t)).To(Equal(expected))
+		},
+		Entry("January is Q1", 1, 1),
+		Entry("March is Q1", 3, 1),
+		Entry("April is Q2", 4, 2),
+		Entry("June is Q2", 6, 2),
+		Entry("July is Q3", 7, 3),
+		Entry("September is Q3", 9, 3),
+		Entry("October is Q4", 10, 4),
+		Entry("December is Q4", 12, 4),
+	)
+})
+
+var _ = Describe("kronos.RollQuarter", func() {
+	date := func(y, m, d int) time.Time {
+		return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	}
+
+	ymd := func(t time.Time) []int {
+		return []int{t.Year(), int(t.Month()), t.Day()}
+	}
+
+	entry := func(arg time.Time, offset int, expected time.Time, note string) TableEntry {
+		desc := fmt.Sprintf("%d/%d/%d + %dq => %d/%d/%d (%s)",
+			arg.Year(), arg.Month(), arg.Day(), offset, expected.Year(), expected.Month(), expected.Day(), note)
+		return Entry(desc, arg, offset, expected)
+	}
+
+	DescribeTable("rolls quarters",
+		func(arg time.Time, offset int, expected time.Time) {
+			actual := kronos.RollQuarter(arg, offset)
+			Expect(ymd(actual)).To(Equal(ymd(expected)))
+		},
+		entry(date(2016, 1, 15), 1, date(2016, 4, 15), "forward a quarter"),
+		entry(date(2016, 3, 31), 1, date(2016, 6, 30), "June has fewer days than March"),
+		entry(date(2016, 11, 30), 1, date(2017, 2, 28), "forward over year boundary into a shorter month"),
+		entry(date(2016, 3, 31), 4, date(2017, 3, 31), "forward 4 quarters"),
+		entry(date(2016, 3, 31), 0, date(2016, 3, 31), "zero quarters"),
+
+		entry(date(2016, 4, 15), -1, date(2016, 1, 15), "back a quarter"),
+		entry(date(2016, 5, 31), -1, date(2016, 2, 29), "back into leap feb"),
+		entry(date(2016, 2, 29), -1, date(2015, 11, 29), "back over year boundary"),
+		entry(date(2016, 1, 31), -1, date(2015, 10, 31), "back over year boundary with same length month"),
+		entry(date(2016, 8, 31), -5, date(2015, 5, 31), "back more than a year"),
 	)
 })
 
@@ -132,6 +231,40 @@ func ExampleBetween() {
 	// 15:05:01
 }
 
+var _ = Describe("kronos.IntervalsBetween", func() {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	It("returns consecutive half-open ranges between start and end", func() {
+		r := kronos.IntervalsBetween(start, start.Add(3*time.Hour), time.Hour)
+		Expect(r).To(Equal([]kronos.TimeRange{
+			{Start: start, End: start.Add(time.Hour)},
+			{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)},
+			{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+		}))
+		Expect(r[0].Contains(start)).To(BeTrue())
+		Expect(r[0].Contains(start.Add(time.Hour))).To(BeFalse())
+		Expect(r[1].Contains(start.Add(time.Hour))).To(BeTrue())
+	})
+
+	It("clamps the final partial range to end", func() {
+		r := kronos.IntervalsBetween(start, start.Add(150*time.Minute), time.Hour)
+		Expect(r).To(HaveLen(3))
+		Expect(r[2]).To(Equal(kronos.TimeRange{Start: start.Add(2 * time.Hour), End: start.Add(150 * time.Minute)}))
+		Expect(r[2].Duration()).To(Equal(30 * time.Minute))
+	})
+
+	It("returns a single range if the interval is longer than the range or not positive", func() {
+		end := start.Add(time.Minute)
+		Expect(kronos.IntervalsBetween(start, end, time.Hour)).To(Equal([]kronos.TimeRange{{Start: start, End: end}}))
+		Expect(kronos.IntervalsBetween(start, end, 0)).To(Equal([]kronos.TimeRange{{Start: start, End: end}}))
+	})
+
+	It("returns nil if end is not after start", func() {
+		Expect(kronos.IntervalsBetween(start, start, time.Hour)).To(BeNil())
+		Expect(kronos.IntervalsBetween(start, start.Add(-time.Hour), time.Hour)).To(BeNil())
+	})
+})
+
 var _ = Describe("kronos.BetweenDates", func() {
 	It("returns a slice of times between start and end", func() {
 		start := time.Now()
@@ -163,6 +296,34 @@ var _ = Describe("kronos.BetweenDates", func() {
 		Expect(kronos.BetweenDates(start, end, 0, 1, 0)).To(Equal([]time.Time{start}))
 	})
 
+	It("contains only start if all offsets are zero", func() {
+		start := time.Now()
+		end := start.AddDate(0, 0, 5)
+		bt := kronos.BetweenDates(start, end, 0, 0, 0)
+		Expect(bt).To(Equal([]time.Time{start}))
+		Expect(bt).To(HaveCap(1))
+	})
+
+	It("contains only start if offsets move backwards", func() {
+		start := time.Now()
+		Expect(kronos.BetweenDates(start, start.AddDate(0, 0, 5), 0, 0, -1)).To(Equal([]time.Time{start}))
+	})
+
+	It("stops once a step with mixed-sign offsets does not move forward", func() {
+		start := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+		end := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+		// Jan 31 -> Feb 1 -> Jan 30, which would go back to Jan 31 and loop forever.
+		Expect(kronos.BetweenDates(start, end, 0, 1, -30)).To(Equal([]time.Time{
+			start,
+			time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		}))
+	})
+
+	It("is empty if all offsets are zero and end is before start", func() {
+		start := time.Now()
+		Expect(kronos.BetweenDates(start, start.AddDate(0, 0, -5), 0, 0, 0)).To(BeEmpty())
+	})
+
 	It("is empty if end is after start", func() {
 		start := time.Now()
 		Expect(kronos.BetweenDates(start, start.Add(time.Hour*24*60*-1), 0, 0, 1)).To(BeEmpty())
@@ -190,3 +351,59 @@ func ExampleBetweenDates() {
 	// 2013-11-22
 	// 2014-11-22
 }
+
+var _ = Describe("kronos.StartOfDay/EndOfDay/StartOfMonth/EndOfMonth", func() {
+	mustLoad := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		Expect(err).ToNot(HaveOccurred())
+		return loc
+	}
+	utc := func(y, m, d, h, min, s, ns int) time.Time {
+		return time.Date(y, time.Month(m), d, h, min, s, ns, time.UTC)
+	}
+
+	DescribeTable("returns day and month boundaries",
+		func(t time.Time, startOfDay, endOfDay, startOfMonth, endOfMonth string) {
+			Expect(kronos.StartOfDay(t).Format(time.RFC3339Nano)).To(Equal(startOfDay))
+			Expect(kronos.EndOfDay(t).Format(time.RFC3339Nano)).To(Equal(endOfDay))
+			Expect(kronos.StartOfMonth(t).Format(time.RFC3339Nano)).To(Equal(startOfMonth))
+			Expect(kronos.EndOfMonth(t).Format(time.RFC3339Nano)).To(Equal(endOfMonth))
+			Expect(kronos.StartOfDay(t).Location()).To(Equal(t.Location()))
+		},
+		Entry("UTC", utc(2015, 6, 15, 13, 4, 5, 6),
+			"2015-06-15T00:00:00Z", "2015-06-15T23:59:59.999999999Z",
+			"2015-06-01T00:00:00Z", "2015-06-30T23:59:59.999999999Z"),
+		Entry("at midnight", utc(2015, 6, 15, 0, 0, 0, 0),
+			"2015-06-15T00:00:00Z", "2015-06-15T23:59:59.999999999Z",
+			"2015-06-01T00:00:00Z", "2015-06-30T23:59:59.999999999Z"),
+		Entry("leap February", utc(2016, 2, 10, 1, 0, 0, 0),
+			"2016-02-10T00:00:00Z", "2016-02-10T23:59:59.999999999Z",
+			"2016-02-01T00:00:00Z", "2016-02-29T23:59:59.999999999Z"),
+		Entry("non-leap February", utc(2015, 2, 28, 23, 59, 59, 999999999),
+			"2015-02-28T00:00:00Z", "2015-02-28T23:59:59.999999999Z",
+			"2015-02-01T00:00:00Z", "2015-02-28T23:59:59.999999999Z"),
+		Entry("December", utc(2015, 12, 31, 12, 0, 0, 0),
+			"2015-12-31T00:00:00Z", "2015-12-31T23:59:59.999999999Z",
+			"2015-12-01T00:00:00Z", "2015-12-31T23:59:59.999999999Z"),
+		Entry("in another location", time.Date(2015, 6, 15, 22, 0, 0, 0, time.FixedZone("X", -7*60*60)),
+			"2015-06-15T00:00:00-07:00", "2015-06-15T23:59:59.999999999-07:00",
+			"2015-06-01T00:00:00-07:00", "2015-06-30T23:59:59.999999999-07:00"),
+	)
+
+	It("handles a spring-forward day", func() {
+		t := time.Date(2024, 3, 10, 12, 0, 0, 0, mustLoad("America/New_York"))
+		start, end := kronos.StartOfDay(t), kronos.EndOfDay(t)
+		Expect(start.Format(time.RFC3339Nano)).To(Equal("2024-03-10T00:00:00-05:00"))
+		Expect(end.Format(time.RFC3339Nano)).To(Equal("2024-03-10T23:59:59.999999999-04:00"))
+		Expect(end.Sub(start)).To(Equal(23*time.Hour - time.Nanosecond))
+	})
+
+	It("handles a spring-forward transition at midnight", func() {
+		// DST started at midnight, so the day started at 01:00.
+		loc := mustLoad("America/Sao_Paulo")
+		t := time.Date(2018, 11, 4, 12, 0, 0, 0, loc)
+		Expect(kronos.StartOfDay(t).Format(time.RFC3339Nano)).To(Equal("2018-11-04T01:00:00-02:00"))
+		Expect(kronos.EndOfDay(t.AddDate(0, 0, -1)).Format(time.RFC3339Nano)).To(Equal("2018-11-03T23:59:59.999999999-03:00"))
+		Expect(kronos.StartOfMonth(t).Format(time.RFC3339Nano)).To(Equal("2018-11-01T00:00:00-03:00"))
+	})
+})
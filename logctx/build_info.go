@@ -0,0 +1,34 @@
+package logctx
+
+import (
+	"runtime/debug"
+)
+
+// BuildInfoFields returns "build_sha", "build_time", and "build_version" fields
+// from the VCS and module info embedded in the binary (see debug.ReadBuildInfo),
+// suitable for NewLoggerInput.Fields.
+// Fields that are not available (like when running with 'go test' or 'go run') are omitted.
+func BuildInfoFields() []any {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	return BuildInfoFieldsFrom(bi)
+}
+
+// BuildInfoFieldsFrom returns the fields described in BuildInfoFields from bi.
+func BuildInfoFieldsFrom(bi *debug.BuildInfo) []any {
+	var fields []any
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			fields = append(fields, "build_sha", s.Value)
+		case "vcs.time":
+			fields = append(fields, "build_time", s.Value)
+		}
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		fields = append(fields, "build_version", v)
+	}
+	return fields
+}
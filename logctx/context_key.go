@@ -0,0 +1,40 @@
+package logctx
+
+import (
+	"context"
+)
+
+// ContextKey is a type-safe key for a context value of type T.
+// Each key created with NewContextKey is distinct, even if it has the same name as another key,
+// so values cannot collide with other packages (or plain string keys).
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey returns a new key for context values of type T.
+// The name is only used for debugging. Usually keys are package variables:
+//
+//	var TenantIdKey = logctx.NewContextKey[string]("tenant_id")
+//
+//	ctx = TenantIdKey.With(ctx, "acme")
+//	tenantId, ok := TenantIdKey.Get(ctx)
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// With returns a new context with v as the value for the key.
+func (k *ContextKey[T]) With(c context.Context, v T) context.Context {
+	return context.WithValue(c, k, v)
+}
+
+// Get returns the value for the key, and true, if c has one.
+// Otherwise, return the zero value of T, and false.
+func (k *ContextKey[T]) Get(c context.Context) (T, bool) {
+	v, ok := c.Value(k).(T)
+	return v, ok
+}
+
+// String returns the name of the key.
+func (k *ContextKey[T]) String() string {
+	return k.name
+}
@@ -0,0 +1,44 @@
+package logctx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewLevelSplitHandler returns a handler that sends records below threshold to low,
+// and records at or above threshold to high.
+// For example, use it to send errors to stderr and everything else to stdout.
+func NewLevelSplitHandler(low, high slog.Handler, threshold slog.Level) *LevelSplitHandler {
+	return &LevelSplitHandler{low: low, high: high, threshold: threshold}
+}
+
+type LevelSplitHandler struct {
+	low       slog.Handler
+	high      slog.Handler
+	threshold slog.Level
+}
+
+var _ slog.Handler = &LevelSplitHandler{}
+
+func (h *LevelSplitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handlerFor(level).Enabled(ctx, level)
+}
+
+func (h *LevelSplitHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handlerFor(r.Level).Handle(ctx, r)
+}
+
+func (h *LevelSplitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewLevelSplitHandler(h.low.WithAttrs(attrs), h.high.WithAttrs(attrs), h.threshold)
+}
+
+func (h *LevelSplitHandler) WithGroup(name string) slog.Handler {
+	return NewLevelSplitHandler(h.low.WithGroup(name), h.high.WithGroup(name), h.threshold)
+}
+
+func (h *LevelSplitHandler) handlerFor(level slog.Level) slog.Handler {
+	if level >= h.threshold {
+		return h.high
+	}
+	return h.low
+}
@@ -2,12 +2,14 @@ package logctx
 
 import (
 	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/phsym/console-slog"
 	"golang.org/x/crypto/ssh/terminal"
 	"io"
 	"log/slog"
 	"os"
+	"reflect"
 )
 
 type IdProviderT func() string
@@ -80,17 +82,36 @@ func Logger(c context.Context) *slog.Logger {
 
 // ActiveTraceId returns the first valid trace value and type from the given context,
 // or MissingTraceIdKey if there is none.
+// Values are coerced with AsString, so a trace id stored as a fmt.Stringer or error
+// is still usable.
 func ActiveTraceId(c context.Context) (TraceIdKey, string) {
-	if trace, ok := c.Value(RequestTraceIdKey).(string); ok {
-		return RequestTraceIdKey, trace
+	for _, key := range []TraceIdKey{RequestTraceIdKey, JobTraceIdKey, ProcessTraceIdKey} {
+		if trace, ok := AsString(c.Value(key)); ok {
+			return key, trace
+		}
 	}
-	if trace, ok := c.Value(JobTraceIdKey).(string); ok {
-		return JobTraceIdKey, trace
+	return MissingTraceIdKey, "no-trace-id-in-context"
+}
+
+// AsString returns v as a string, and true, if v is a string, error, fmt.Stringer,
+// or a type with an underlying string kind.
+// Return "", false for other values (including nil).
+func AsString(v any) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return t, true
+	case error:
+		return t.Error(), true
+	case fmt.Stringer:
+		return t.String(), true
 	}
-	if trace, ok := c.Value(ProcessTraceIdKey).(string); ok {
-		return ProcessTraceIdKey, trace
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return rv.String(), true
 	}
-	return MissingTraceIdKey, "no-trace-id-in-context"
+	return "", false
 }
 
 // ActiveTraceIdValue returns the value part of ActiveTraceId (does not return the TradeIdKey type part).
@@ -126,7 +147,18 @@ type NewLoggerInput struct {
 	// If IsTty, log to os.Stderr.
 	// Otherwise, log to os.Stdout.
 	Out io.Writer
+	// DisableTTYDetection, if true, ignores IsTty when choosing the output and format,
+	// so the logger behaves as it would without a terminal (JSON to os.Stdout by default).
+	// Useful in CI, where a pseudo-TTY may be allocated but logs still need to be parsed.
+	DisableTTYDetection bool
+	// ErrOut, if set, receives records at slog.LevelError and above,
+	// and Out (or File, etc.) receives everything below.
+	// For example, set Out to os.Stdout and ErrOut to os.Stderr.
+	// Both streams use the same Format.
+	ErrOut io.Writer
 	// BuildSha will add "build_sha" to the logger fields, if not empty.
+	// To use the VCS info embedded by the Go toolchain instead,
+	// pass BuildInfoFields() in Fields.
 	BuildSha string
 	// BuildTime will add "build_time" to the logger fields, it not empty.
 	BuildTime string
@@ -142,6 +174,15 @@ type NewLoggerInput struct {
 	Fields []any
 }
 
+// NewLogger returns a new logger configured by cfg.
+//
+// The output stream is chosen using the first of these that applies:
+// Out, File, os.Stderr if a TTY is detected, or os.Stdout.
+//
+// The format is chosen using the first of these that applies:
+// Format, 'json' if File is set, colored console output if a TTY is detected, or 'json'.
+//
+// TTY detection (see IsTty) can be turned off with DisableTTYDetection.
 func NewLogger(cfg NewLoggerInput) (*slog.Logger, error) {
 	// Set output to file or stdout/stderr (stderr for tty, stdout otherwise like for 12 factor apps)
 	var out io.Writer
@@ -153,7 +194,7 @@ func NewLogger(cfg NewLoggerInput) (*slog.Logger, error) {
 			return nil, err
 		}
 		out = file
-	} else if IsTty() {
+	} else if cfg.isTty() {
 		out = os.Stderr
 	} else {
 		out = os.Stdout
@@ -166,20 +207,9 @@ func NewLogger(cfg NewLoggerInput) (*slog.Logger, error) {
 	}
 	hopts.Level = lvl
 
-	var handler slog.Handler
-	if cfg.Format == "json" {
-		handler = slog.NewJSONHandler(out, hopts)
-	} else if cfg.Format == "text" {
-		handler = slog.NewTextHandler(out, hopts)
-	} else if cfg.File != "" {
-		handler = slog.NewJSONHandler(out, hopts)
-	} else if IsTty() {
-		handler = console.NewHandler(out, &console.HandlerOptions{
-			AddSource: hopts.AddSource,
-			Level:     hopts.Level,
-		})
-	} else {
-		handler = slog.NewJSONHandler(out, hopts)
+	handler := newFormatHandler(cfg, out, hopts)
+	if cfg.ErrOut != nil {
+		handler = NewLevelSplitHandler(handler, newFormatHandler(cfg, cfg.ErrOut, hopts), slog.LevelError)
 	}
 	if cfg.MakeHandler != nil {
 		handler = cfg.MakeHandler(hopts, handler)
@@ -198,6 +228,26 @@ func NewLogger(cfg NewLoggerInput) (*slog.Logger, error) {
 	return logger, nil
 }
 
+func newFormatHandler(cfg NewLoggerInput, out io.Writer, hopts *slog.HandlerOptions) slog.Handler {
+	if cfg.Format == "json" {
+		return slog.NewJSONHandler(out, hopts)
+	} else if cfg.Format == "text" {
+		return slog.NewTextHandler(out, hopts)
+	} else if cfg.File != "" {
+		return slog.NewJSONHandler(out, hopts)
+	} else if cfg.isTty() {
+		return console.NewHandler(out, &console.HandlerOptions{
+			AddSource: hopts.AddSource,
+			Level:     hopts.Level,
+		})
+	}
+	return slog.NewJSONHandler(out, hopts)
+}
+
+func (cfg NewLoggerInput) isTty() bool {
+	return !cfg.DisableTTYDetection && IsTty()
+}
+
 func IsTty() bool {
 	return terminal.IsTerminal(int(os.Stdout.Fd()))
 }
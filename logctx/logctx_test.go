@@ -1,11 +1,15 @@
 package logctx_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/lithictech/go-aperitif/v2/logctx"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"log/slog"
+	"runtime/debug"
 	"testing"
 )
 
@@ -66,6 +70,19 @@ var _ = Describe("logtools", func() {
 			key, _ = logctx.ActiveTraceId(c)
 			Expect(key).To(Equal(logctx.RequestTraceIdKey))
 		})
+		It("uses the message of an error trace value", func() {
+			c := context.WithValue(ctx, logctx.RequestTraceIdKey, errors.New("errtrace"))
+			key, val := logctx.ActiveTraceId(c)
+			Expect(key).To(Equal(logctx.RequestTraceIdKey))
+			Expect(val).To(Equal("errtrace"))
+		})
+		It("skips trace values that cannot be converted to a string", func() {
+			c := context.WithValue(ctx, logctx.RequestTraceIdKey, 5)
+			c = context.WithValue(c, logctx.JobTraceIdKey, "job")
+			key, val := logctx.ActiveTraceId(c)
+			Expect(key).To(Equal(logctx.JobTraceIdKey))
+			Expect(val).To(Equal("job"))
+		})
 		It("defaults to a missing trace id", func() {
 			key, val := logctx.ActiveTraceId(ctx)
 			Expect(key).To(Equal(logctx.MissingTraceIdKey))
@@ -73,6 +90,28 @@ var _ = Describe("logtools", func() {
 		})
 	})
 
+	Describe("AsString", func() {
+		type myString string
+		It("converts string-like values", func() {
+			for v, expected := range map[any]string{
+				"a":                      "a",
+				errors.New("b"):          "b",
+				logctx.RequestTraceIdKey: "trace_id",
+				myString("c"):            "c",
+			} {
+				s, ok := logctx.AsString(v)
+				Expect(ok).To(BeTrue())
+				Expect(s).To(Equal(expected))
+			}
+		})
+		It("returns false for other values", func() {
+			_, ok := logctx.AsString(5)
+			Expect(ok).To(BeFalse())
+			_, ok = logctx.AsString(nil)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	Describe("WithLogger", func() {
 		It("adds the logger", func() {
 			c := logctx.WithLogger(context.Background(), logger)
@@ -142,4 +181,148 @@ var _ = Describe("logtools", func() {
 			Expect(hook.Records()[2].AttrMap()).To(BeEquivalentTo(map[string]any{"trace_id": "mytrace", "span_id": "myspan"}))
 		})
 	})
+
+	Describe("ContextKey", func() {
+		It("gets and sets typed values", func() {
+			key := logctx.NewContextKey[int]("count")
+			_, ok := key.Get(ctx)
+			Expect(ok).To(BeFalse())
+			c := key.With(ctx, 5)
+			v, ok := key.Get(c)
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(5))
+			Expect(key.String()).To(Equal("count"))
+		})
+		It("does not collide with other keys with the same name", func() {
+			key1 := logctx.NewContextKey[string]("tenant")
+			key2 := logctx.NewContextKey[string]("tenant")
+			c := key1.With(ctx, "acme")
+			c = context.WithValue(c, "tenant", "plain")
+			_, ok := key2.Get(c)
+			Expect(ok).To(BeFalse())
+			v, _ := key1.Get(c)
+			Expect(v).To(Equal("acme"))
+		})
+	})
+
+	Describe("SamplingHandler", func() {
+		// Find trace ids that are sampled in and out at the rate, since the decision is a hash.
+		findTrace := func(rate float64, sampled bool) string {
+			for i := 0; ; i++ {
+				t := fmt.Sprintf("trace-%d", i)
+				if logctx.SampleTraceId(t, rate) == sampled {
+					return t
+				}
+			}
+		}
+
+		It("keeps or drops all records for a trace", func() {
+			lg := slog.New(logctx.NewSamplingHandler(hook, 0.5))
+			inCtx := context.WithValue(ctx, logctx.RequestTraceIdKey, findTrace(0.5, true))
+			outCtx := context.WithValue(ctx, logctx.RequestTraceIdKey, findTrace(0.5, false))
+			for i := 0; i < 3; i++ {
+				lg.InfoContext(inCtx, "in")
+				lg.InfoContext(outCtx, "out")
+			}
+			Expect(hook.Records()).To(HaveLen(3))
+			Expect(hook.LastRecord().Record.Message).To(Equal("in"))
+		})
+		It("keeps records without a trace", func() {
+			lg := slog.New(logctx.NewSamplingHandler(hook, 0))
+			lg.InfoContext(ctx, "hi")
+			lg.Info("hi")
+			Expect(hook.Records()).To(HaveLen(2))
+		})
+		It("uses the decision stored in the context", func() {
+			lg := slog.New(logctx.NewSamplingHandler(hook, 1)).With("x", 1)
+			c := context.WithValue(ctx, logctx.RequestTraceIdKey, findTrace(0.5, false))
+			c = logctx.WithSamplingDecision(c, 0.5)
+			lg.InfoContext(c, "out")
+			Expect(hook.Records()).To(BeEmpty())
+			lg.InfoContext(logctx.WithSampled(c, true), "in")
+			Expect(hook.Records()).To(HaveLen(1))
+		})
+		It("only makes the decision once", func() {
+			c := context.WithValue(ctx, logctx.RequestTraceIdKey, findTrace(0.5, true))
+			c = logctx.WithSamplingDecision(c, 0.5)
+			c = logctx.WithSamplingDecision(c, 0)
+			sampled, ok := logctx.SamplingDecision(c)
+			Expect(ok).To(BeTrue())
+			Expect(sampled).To(BeTrue())
+		})
+		It("always samples at a rate of 1 and never at a rate of 0", func() {
+			Expect(logctx.SampleTraceId("abc", 1)).To(BeTrue())
+			Expect(logctx.SampleTraceId("abc", 0)).To(BeFalse())
+		})
+	})
+
+	Describe("RingHandler", func() {
+		It("passes records through and retains the most recent ones", func() {
+			ring := logctx.NewRingHandler(hook, 3)
+			lg := slog.New(ring).With("x", 1)
+			for i := 0; i < 5; i++ {
+				lg.Info("msg", "i", i)
+			}
+			Expect(hook.Records()).To(HaveLen(5))
+			recs := ring.Records()
+			Expect(recs).To(HaveLen(3))
+			Expect(recs[0].AttrMap()).To(HaveKeyWithValue("i", BeEquivalentTo(2)))
+			Expect(recs[0].AttrMap()).To(HaveKeyWithValue("x", BeEquivalentTo(1)))
+			Expect(recs[2].AttrMap()).To(HaveKeyWithValue("i", BeEquivalentTo(4)))
+		})
+		It("returns only records that were logged before the buffer is full", func() {
+			ring := logctx.NewRingHandler(hook, 3)
+			slog.New(ring).Info("hi")
+			Expect(ring.Records()).To(HaveLen(1))
+			Expect(ring.Records()[0].Record.Message).To(Equal("hi"))
+		})
+		It("panics for an invalid capacity", func() {
+			Expect(func() { logctx.NewRingHandler(hook, 0) }).To(Panic())
+		})
+	})
+
+	Describe("NewLogger", func() {
+		It("can split output by level", func() {
+			out := bytes.NewBuffer(nil)
+			errOut := bytes.NewBuffer(nil)
+			lg, err := logctx.NewLogger(logctx.NewLoggerInput{Level: "debug", Format: "json", Out: out, ErrOut: errOut})
+			Expect(err).ToNot(HaveOccurred())
+			lg = lg.With("x", 1)
+			lg.Info("infomsg")
+			lg.Warn("warnmsg")
+			lg.Error("errmsg")
+			Expect(out.String()).To(And(ContainSubstring("infomsg"), ContainSubstring("warnmsg"), Not(ContainSubstring("errmsg"))))
+			Expect(errOut.String()).To(And(ContainSubstring("errmsg"), ContainSubstring(`"x":1`), Not(ContainSubstring("infomsg"))))
+		})
+		It("logs JSON when TTY detection is disabled and no format is given", func() {
+			out := bytes.NewBuffer(nil)
+			lg, err := logctx.NewLogger(logctx.NewLoggerInput{Level: "info", Out: out, DisableTTYDetection: true})
+			Expect(err).ToNot(HaveOccurred())
+			lg.Info("hi")
+			Expect(out.String()).To(HavePrefix(`{"time":`))
+			Expect(out.String()).To(ContainSubstring(`"msg":"hi"`))
+		})
+	})
+
+	Describe("BuildInfoFieldsFrom", func() {
+		It("extracts VCS and version fields", func() {
+			bi := &debug.BuildInfo{
+				Main: debug.Module{Version: "v1.2.3"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs", Value: "git"},
+					{Key: "vcs.revision", Value: "abc123"},
+					{Key: "vcs.time", Value: "2024-01-02T03:04:05Z"},
+				},
+			}
+			Expect(logctx.BuildInfoFieldsFrom(bi)).To(Equal([]any{
+				"build_sha", "abc123",
+				"build_time", "2024-01-02T03:04:05Z",
+				"build_version", "v1.2.3",
+			}))
+		})
+		It("omits missing fields and development versions", func() {
+			bi := &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}
+			Expect(logctx.BuildInfoFieldsFrom(bi)).To(BeEmpty())
+		})
+	})
 })
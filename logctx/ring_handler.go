@@ -0,0 +1,102 @@
+package logctx
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// NewRingHandler returns a handler that passes records to next,
+// and also retains the most recent capacity records in memory,
+// such as to serve from a /debug/logs admin endpoint.
+// Panics if capacity is not positive.
+func NewRingHandler(next slog.Handler, capacity int) *RingHandler {
+	if capacity <= 0 {
+		panic("ring handler capacity must be > 0")
+	}
+	return &RingHandler{
+		next: next,
+		ring: &ringRecords{r: make([]HookRecord, capacity)},
+	}
+}
+
+// RingHandler is an slog.Handler that keeps a fixed number of recent records.
+// It is safe for concurrent use,
+// and handlers derived with WithAttrs or WithGroup share the same buffer.
+type RingHandler struct {
+	next  slog.Handler
+	ring  *ringRecords
+	attrs []slog.Attr
+	group string
+}
+
+var _ slog.Handler = &RingHandler{}
+
+func (h *RingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RingHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, len(h.attrs), len(h.attrs)+r.NumAttrs())
+	copy(attrs, h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	h.ring.Add(HookRecord{Record: r.Clone(), Attrs: attrs, Group: h.group})
+	return h.next.Handle(ctx, r)
+}
+
+func (h *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &RingHandler{
+		next:  h.next.WithAttrs(attrs),
+		ring:  h.ring,
+		attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+		group: h.group,
+	}
+}
+
+func (h *RingHandler) WithGroup(group string) slog.Handler {
+	return &RingHandler{
+		next:  h.next.WithGroup(group),
+		ring:  h.ring,
+		attrs: h.attrs,
+		group: group,
+	}
+}
+
+// Records returns the retained records, oldest first.
+func (h *RingHandler) Records() []HookRecord {
+	return h.ring.Records()
+}
+
+type ringRecords struct {
+	r    []HookRecord
+	next int
+	full bool
+	mux  sync.RWMutex
+}
+
+func (rr *ringRecords) Add(r HookRecord) {
+	rr.mux.Lock()
+	defer rr.mux.Unlock()
+	rr.r[rr.next] = r
+	rr.next = (rr.next + 1) % len(rr.r)
+	if rr.next == 0 {
+		rr.full = true
+	}
+}
+
+func (rr *ringRecords) Records() []HookRecord {
+	rr.mux.RLock()
+	defer rr.mux.RUnlock()
+	if !rr.full {
+		result := make([]HookRecord, rr.next)
+		copy(result, rr.r[:rr.next])
+		return result
+	}
+	result := make([]HookRecord, 0, len(rr.r))
+	result = append(result, rr.r[rr.next:]...)
+	result = append(result, rr.r[:rr.next]...)
+	return result
+}
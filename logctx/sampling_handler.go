@@ -0,0 +1,103 @@
+package logctx
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"math"
+)
+
+var samplingDecisionKey = NewContextKey[bool]("sampled")
+
+// WithSamplingDecision returns a new context with a decision about whether
+// logs for the active trace (see ActiveTraceId) are sampled in, at the given rate (0 to 1).
+// The decision is based on a hash of the trace id, so it is the same for every log of a trace,
+// and every process that sees the same trace id.
+// If the context already has a decision, it is kept, so the decision is only made once.
+// If there is no trace id, the decision is to sample in.
+func WithSamplingDecision(c context.Context, rate float64) context.Context {
+	if _, ok := SamplingDecision(c); ok {
+		return c
+	}
+	return WithSampled(c, sampleContext(c, rate))
+}
+
+// WithSampled returns a new context with an explicit sampling decision,
+// such as to always keep the logs for a request with a debug header.
+func WithSampled(c context.Context, sampled bool) context.Context {
+	return samplingDecisionKey.With(c, sampled)
+}
+
+// SamplingDecision returns whether the logs for c are sampled in,
+// and true if a decision has been made (see WithSamplingDecision and WithSampled).
+func SamplingDecision(c context.Context) (sampled bool, ok bool) {
+	return samplingDecisionKey.Get(c)
+}
+
+// SampleTraceId returns true if traceId is sampled in at rate (0 to 1).
+// The result is deterministic for a trace id.
+func SampleTraceId(traceId string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(traceId))
+	return float64(h.Sum64()) < rate*math.MaxUint64
+}
+
+func sampleContext(c context.Context, rate float64) bool {
+	key, trace := ActiveTraceId(c)
+	if key == MissingTraceIdKey {
+		return true
+	}
+	return SampleTraceId(trace, rate)
+}
+
+// NewSamplingHandler returns a handler that only passes records to h
+// if their context is sampled in, so all the logs of a request or job are kept or dropped together.
+// It uses the decision in the context (see WithSamplingDecision), if there is one.
+// Otherwise, it samples based on the active trace id at rate (0 to 1),
+// which is the same decision WithSamplingDecision would make.
+// Records without a trace id in their context are always kept.
+func NewSamplingHandler(h slog.Handler, rate float64) *SamplingHandler {
+	return &SamplingHandler{h: h, rate: rate}
+}
+
+type SamplingHandler struct {
+	h    slog.Handler
+	rate float64
+}
+
+var _ slog.Handler = &SamplingHandler{}
+
+func (s *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.sampled(ctx) && s.h.Enabled(ctx, level)
+}
+
+func (s *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if !s.sampled(ctx) {
+		return nil
+	}
+	return s.h.Handle(ctx, record)
+}
+
+func (s *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewSamplingHandler(s.h.WithAttrs(attrs), s.rate)
+}
+
+func (s *SamplingHandler) WithGroup(name string) slog.Handler {
+	return NewSamplingHandler(s.h.WithGroup(name), s.rate)
+}
+
+func (s *SamplingHandler) sampled(ctx context.Context) bool {
+	if ctx == nil {
+		return true
+	}
+	if sampled, ok := SamplingDecision(ctx); ok {
+		return sampled
+	}
+	return sampleContext(ctx, s.rate)
+}
@@ -20,8 +20,13 @@
 // (ie, use MARIOBROS=1 to poll every second).
 // You can specify your own overrides.
 //
+// To limit overhead, you can track only some goroutines by setting Options.Prefixes,
+// like []string{"jobs."}. Calls to Yo with names that do not start with one of the prefixes noop.
+//
 // If Mariobros is not active, calls to Yo noop and the timer that prints does not run.
 // It's important to call Mariobros.Start() early, or import mariobros/autoload.
+//
+// Call mariobros.Stop to stop reporting and tracking, like at the end of a test.
 package mariobros
 
 import (
@@ -93,6 +98,10 @@ type mariobros struct {
 	enabledFast       int64
 	writer            Writer
 	interval          time.Duration
+	// prefixes is read by Yo without the lock, so must be replaced atomically.
+	prefixes atomic.Pointer[[]string]
+	done     chan struct{}
+	exited   chan struct{}
 }
 
 func newMariobros() *mariobros {
@@ -111,32 +120,68 @@ func (mb *mariobros) Start(opts Options) {
 	if mb.enabledFast == 1 {
 		return
 	}
-	atomic.StoreInt64(&mb.enabledFast, 1)
 	mb.interval = opts.Interval
 	mb.writer = opts.Writer
+	prefixes := append([]string(nil), opts.Prefixes...)
+	mb.prefixes.Store(&prefixes)
+	atomic.StoreInt64(&mb.enabledFast, 1)
+	mb.done = make(chan struct{})
+	mb.exited = make(chan struct{})
 	t := time.NewTicker(mb.interval)
-	go func() {
+	go func(done, exited chan struct{}) {
+		defer close(exited)
+		defer t.Stop()
 		for {
 			select {
+			case <-done:
+				return
 			case <-t.C:
 			}
-			activePerName := make(map[string][]GoroutineId, len(mb.goroutineRegistry)+1)
-			for name, active := range mb.goroutineRegistry {
-				if len(active) > 0 {
-					for id := range active {
-						activePerName[name] = append(activePerName[name], id)
-					}
-				}
+			mb.writer(mb.Snapshot())
+		}
+	}(mb.done, mb.exited)
+}
+
+// Stop stops the reporting goroutine, and waits for it to exit.
+// Calls to Yo noop after Stop, until Start is called again.
+// Goroutines that are already being tracked remain in the registry.
+func (mb *mariobros) Stop() {
+	mb.mutex.Lock()
+	if mb.enabledFast == 0 {
+		mb.mutex.Unlock()
+		return
+	}
+	atomic.StoreInt64(&mb.enabledFast, 0)
+	close(mb.done)
+	exited := mb.exited
+	mb.mutex.Unlock()
+	// Wait outside the lock, since the reporting goroutine takes it to write a snapshot.
+	<-exited
+}
+
+// Snapshot returns the number of active goroutines, and the ids of the active goroutines for each name.
+// Names with no active goroutines are not included.
+func (mb *mariobros) Snapshot() (uint, map[string][]GoroutineId) {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+	activePerName := make(map[string][]GoroutineId, len(mb.goroutineRegistry)+1)
+	for name, active := range mb.goroutineRegistry {
+		if len(active) > 0 {
+			for id := range active {
+				activePerName[name] = append(activePerName[name], id)
 			}
-			mb.writer(mb.activeGoroutines, activePerName)
 		}
-	}()
+	}
+	return mb.activeGoroutines, activePerName
 }
 
 func (mb *mariobros) Yo(name string) func() {
 	if atomic.LoadInt64(&mb.enabledFast) == 0 {
 		return noop
 	}
+	if !mb.tracks(name) {
+		return noop
+	}
 	mb.mutex.Lock()
 	defer mb.mutex.Unlock()
 	if atomic.LoadInt64(&mb.enabledFast) == 0 {
@@ -159,6 +204,19 @@ func (mb *mariobros) Yo(name string) func() {
 	}
 }
 
+func (mb *mariobros) tracks(name string) bool {
+	prefixes := mb.prefixes.Load()
+	if prefixes == nil || len(*prefixes) == 0 {
+		return true
+	}
+	for _, p := range *prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	instance = newMariobros()
 }
@@ -167,13 +225,28 @@ func Start(opts Options) {
 	instance.Start(opts)
 }
 
+func Stop() {
+	instance.Stop()
+}
+
 func Yo(name string) func() {
 	return instance.Yo(name)
 }
 
+// Snapshot returns the current state of the goroutine registry, synchronously.
+// This is useful for asserting that code does not leak goroutines in tests,
+// like checking there are no active goroutines for a name after an operation completes.
+// Note that goroutines are only tracked while mariobros is started.
+func Snapshot() (total uint, perName map[string][]GoroutineId) {
+	return instance.Snapshot()
+}
+
 type Options struct {
 	Interval time.Duration
 	Writer   Writer
+	// Prefixes limits tracking to goroutines with names starting with one of the prefixes,
+	// like "jobs.". If empty, all goroutines are tracked.
+	Prefixes []string
 }
 
 type OptionModifier func(*Options)
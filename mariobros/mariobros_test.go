@@ -0,0 +1,206 @@
+package mariobros_test
+
+import (
+	"github.com/lithictech/go-aperitif/v2/mariobros"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMariobros(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "mariobros package Suite")
+}
+
+var _ = Describe("mariobros", func() {
+	var writes *int64
+
+	// start starts mariobros with a writer that counts how often it is called,
+	// and stops it after the test.
+	start := func(mods ...mariobros.OptionModifier) {
+		mods = append([]mariobros.OptionModifier{func(o *mariobros.Options) {
+			o.Interval = time.Millisecond
+			o.Writer = func(uint, map[string][]mariobros.GoroutineId) {
+				atomic.AddInt64(writes, 1)
+			}
+		}}, mods...)
+		mariobros.Start(mariobros.NewOptions(mods...))
+		DeferCleanup(mariobros.Stop)
+	}
+
+	BeforeEach(func() {
+		writes = new(int64)
+	})
+
+	Describe("Stop", func() {
+		It("stops calling the writer", func() {
+			start()
+			Eventually(func() int64 { return atomic.LoadInt64(writes) }).Should(BeNumerically(">", 0))
+			mariobros.Stop()
+			stoppedAt := atomic.LoadInt64(writes)
+			Consistently(func() int64 { return atomic.LoadInt64(writes) }, "20ms", "2ms").Should(Equal(stoppedAt))
+		})
+
+		It("noops if not started, or already stopped", func() {
+			mariobros.Stop()
+			start()
+			mariobros.Stop()
+			mariobros.Stop()
+		})
+
+		It("makes Yo noop", func() {
+			start()
+			mariobros.Stop()
+			done := mariobros.Yo("stopped.job")
+			total, perName := mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(0))
+			Expect(perName).ToNot(HaveKey("stopped.job"))
+			done()
+			total, _ = mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(0))
+		})
+
+		It("can be restarted", func() {
+			start()
+			mariobros.Stop()
+			restartWrites := new(int64)
+			start(func(o *mariobros.Options) {
+				o.Writer = func(uint, map[string][]mariobros.GoroutineId) {
+					atomic.AddInt64(restartWrites, 1)
+				}
+			})
+			Eventually(func() int64 { return atomic.LoadInt64(restartWrites) }).Should(BeNumerically(">", 0))
+			done := mariobros.Yo("restarted.job")
+			_, perName := mariobros.Snapshot()
+			Expect(perName).To(HaveKeyWithValue("restarted.job", HaveLen(1)))
+			done()
+		})
+
+		It("is safe to call concurrently", func() {
+			start()
+			finished := make(chan struct{})
+			for i := 0; i < 4; i++ {
+				go func() {
+					defer GinkgoRecover()
+					mariobros.Stop()
+					finished <- struct{}{}
+				}()
+			}
+			for i := 0; i < 4; i++ {
+				Eventually(finished).Should(Receive())
+			}
+		})
+	})
+
+	Describe("Prefixes", func() {
+		It("only tracks names starting with a prefix", func() {
+			start(func(o *mariobros.Options) {
+				o.Prefixes = []string{"jobs.", "workers."}
+			})
+			untracked := mariobros.Yo("http.request")
+			tracked1 := mariobros.Yo("jobs.email")
+			tracked2 := mariobros.Yo("workers.sync")
+			total, perName := mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(2))
+			Expect(perName).To(HaveKey("jobs.email"))
+			Expect(perName).To(HaveKey("workers.sync"))
+			Expect(perName).ToNot(HaveKey("http.request"))
+			untracked()
+			tracked1()
+			tracked2()
+			total, _ = mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(0))
+		})
+
+		It("tracks everything if empty, including after a restart with prefixes", func() {
+			start(func(o *mariobros.Options) {
+				o.Prefixes = []string{"jobs."}
+			})
+			mariobros.Stop()
+			start()
+			done := mariobros.Yo("http.request")
+			_, perName := mariobros.Snapshot()
+			Expect(perName).To(HaveKey("http.request"))
+			done()
+		})
+
+		It("can be changed while Yo is called concurrently", func() {
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							mariobros.Yo("jobs.concurrent")()
+						}
+					}
+				}()
+			}
+			for i := 0; i < 500; i++ {
+				mariobros.Start(mariobros.NewOptions(func(o *mariobros.Options) {
+					o.Interval = time.Millisecond
+					o.Writer = func(uint, map[string][]mariobros.GoroutineId) {}
+					o.Prefixes = []string{"jobs."}
+				}))
+				mariobros.Stop()
+			}
+			close(stop)
+			wg.Wait()
+			total, _ := mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(0))
+		})
+	})
+
+	Describe("Snapshot", func() {
+		It("reports the active goroutines until their done funcs are called", func() {
+			start()
+			done1 := mariobros.Yo("snapshot.job")
+			done2 := mariobros.Yo("snapshot.job")
+			total, perName := mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(2))
+			Expect(perName).To(HaveLen(1))
+			Expect(perName).To(HaveKeyWithValue("snapshot.job", HaveLen(2)))
+			ids := perName["snapshot.job"]
+			Expect(ids[0]).ToNot(Equal(ids[1]))
+
+			done1()
+			total, perName = mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(1))
+			Expect(perName).To(HaveKeyWithValue("snapshot.job", HaveLen(1)))
+
+			done2()
+			total, perName = mariobros.Snapshot()
+			Expect(total).To(BeEquivalentTo(0))
+			Expect(perName).ToNot(HaveKey("snapshot.job"))
+		})
+
+		It("passes the same state to the writer", func() {
+			type report struct {
+				total   uint
+				perName map[string][]mariobros.GoroutineId
+			}
+			reports := make(chan report, 100)
+			start(func(o *mariobros.Options) {
+				o.Writer = func(total uint, perName map[string][]mariobros.GoroutineId) {
+					select {
+					case reports <- report{total, perName}:
+					default:
+					}
+				}
+			})
+			done := mariobros.Yo("written.job")
+			defer done()
+			Eventually(reports).Should(Receive(Satisfy(func(r report) bool {
+				return r.total == 1 && len(r.perName["written.job"]) == 1
+			})))
+		})
+	})
+})
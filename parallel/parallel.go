@@ -2,9 +2,12 @@ package parallel
 
 import (
 	"errors"
+	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/lithictech/go-aperitif/v2/mariobros"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var ErrInvalidParallelism = errors.New("degree of parallelism must be > 0")
@@ -25,6 +28,27 @@ type Processor func(idx int) error
 // and assign to the slice index while processing.
 // See ParallelForFiles for an example usage.
 func ForEach(total int, n int, process Processor) error {
+	return ForEachWith(total, n, process, ForEachOpts{})
+}
+
+type ForEachOpts struct {
+	// OnItemDone, if provided, is called after each call to process completes,
+	// with the index of the item, how long process took, and the error it returned.
+	// It is called from multiple goroutines concurrently,
+	// so callers must handle their own synchronization.
+	OnItemDone func(idx int, d time.Duration, err error)
+	// OnProgress, if provided, is called after each item completes,
+	// with the number of completed items and the total.
+	// Each call gets a distinct completed count, and the last item to finish gets total.
+	// Like OnItemDone, it is called from multiple goroutines concurrently,
+	// so calls may overlap and arrive out of order.
+	// It is called after the item releases its slot, so a slow callback
+	// does not hold up processing the other items.
+	OnProgress func(completed, total int)
+}
+
+// ForEachWith is ForEach with additional options, like for observing each item.
+func ForEachWith(total int, n int, process Processor, opts ForEachOpts) error {
 	if n <= 0 {
 		return ErrInvalidParallelism
 	}
@@ -32,6 +56,8 @@ func ForEach(total int, n int, process Processor) error {
 	semaphore := make(chan empty, n)
 	errs := make([]error, total)
 
+	var completed int64
+
 	wg := sync.WaitGroup{}
 	wg.Add(total)
 	for i := 0; i < total; i++ {
@@ -39,11 +65,53 @@ func ForEach(total int, n int, process Processor) error {
 			mario := mariobros.Yo("parallel.foreach")
 			defer mario()
 			semaphore <- empty{}
-			errs[i] = process(i)
+			if opts.OnItemDone == nil {
+				errs[i] = process(i)
+			} else {
+				start := time.Now()
+				errs[i] = process(i)
+				opts.OnItemDone(i, time.Since(start), errs[i])
+			}
 			<-semaphore
+			if opts.OnProgress != nil {
+				opts.OnProgress(int(atomic.AddInt64(&completed, 1)), total)
+			}
 			wg.Done()
 		}(i)
 	}
 	wg.Wait()
 	return multierror.Append(nil, errs...).ErrorOrNil()
 }
+
+// MapPartial calls fn for each item in parallel (with n as the degree of parallelism),
+// and returns the results and errors, both index-aligned with items.
+// Unlike ForEach, errors are not coalesced, so callers can use the successful results
+// while handling the failures individually.
+// If fn errors for an item, its result is the zero value.
+// If fn panics for an item, the panic is recovered and returned as the error for that item.
+// If n is invalid, every item has ErrInvalidParallelism as its error.
+func MapPartial[T, R any](items []T, n int, fn func(T) (R, error)) ([]R, []error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	if n <= 0 {
+		for i := range errs {
+			errs[i] = ErrInvalidParallelism
+		}
+		return results, errs
+	}
+	_ = ForEach(len(items), n, func(idx int) error {
+		defer func() {
+			if r := recover(); r != nil {
+				errs[idx] = fmt.Errorf("panic processing item %d: %v", idx, r)
+			}
+		}()
+		r, err := fn(items[idx])
+		if err != nil {
+			errs[idx] = err
+			return nil
+		}
+		results[idx] = r
+		return nil
+	})
+	return results, errs
+}
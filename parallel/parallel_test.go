@@ -1,11 +1,15 @@
 package parallel_test
 
 import (
+	"errors"
 	"github.com/lithictech/go-aperitif/v2/parallel"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestParallel(t *testing.T) {
@@ -41,4 +45,93 @@ var _ = Describe("ParallelFor", func() {
 		err := parallel.ForEach(1, 0, nil)
 		Expect(err).To(BeIdenticalTo(parallel.ErrInvalidParallelism))
 	})
+
+	It("can observe each item", func() {
+		mux := sync.Mutex{}
+		done := make(map[int]error)
+		err := parallel.ForEachWith(10, 3, func(idx int) error {
+			if idx == 4 {
+				return errors.New("four")
+			}
+			return nil
+		}, parallel.ForEachOpts{
+			OnItemDone: func(idx int, d time.Duration, err error) {
+				mux.Lock()
+				defer mux.Unlock()
+				Expect(d).To(BeNumerically(">=", 0))
+				done[idx] = err
+			},
+		})
+		Expect(err).To(MatchError(ContainSubstring("four")))
+		Expect(done).To(HaveLen(10))
+		Expect(done[3]).ToNot(HaveOccurred())
+		Expect(done[4]).To(MatchError("four"))
+	})
+
+	It("can report progress", func() {
+		var mu sync.Mutex
+		var progress []int
+		err := parallel.ForEachWith(100, 5, func(idx int) error {
+			return nil
+		}, parallel.ForEachOpts{
+			OnProgress: func(completed, total int) {
+				defer GinkgoRecover()
+				Expect(total).To(Equal(100))
+				mu.Lock()
+				defer mu.Unlock()
+				progress = append(progress, completed)
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		expected := make([]int, 100)
+		for i := range expected {
+			expected[i] = i + 1
+		}
+		Expect(progress).To(ConsistOf(expected))
+	})
+
+	It("does not hold up processing while reporting progress", func() {
+		var started int64
+		allStarted := make(chan struct{})
+		err := parallel.ForEachWith(4, 2, func(idx int) error {
+			if atomic.AddInt64(&started, 1) == 4 {
+				close(allStarted)
+			}
+			return nil
+		}, parallel.ForEachOpts{
+			OnProgress: func(completed, total int) {
+				// If the callback held a slot, only 2 items could ever start.
+				select {
+				case <-allStarted:
+				case <-time.After(5 * time.Second):
+				}
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allStarted).To(BeClosed())
+	})
+})
+
+var _ = Describe("MapPartial", func() {
+	It("returns index-aligned results and errors, recovering panics", func() {
+		results, errs := parallel.MapPartial([]int{1, 2, 3, 4}, 2, func(i int) (string, error) {
+			switch i {
+			case 2:
+				return "ignored", errors.New("two")
+			case 3:
+				panic("three")
+			}
+			return strconv.Itoa(i * 10), nil
+		})
+		Expect(results).To(Equal([]string{"10", "", "", "40"}))
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0]).ToNot(HaveOccurred())
+		Expect(errs[1]).To(MatchError("two"))
+		Expect(errs[2]).To(MatchError(ContainSubstring("three")))
+		Expect(errs[3]).ToNot(HaveOccurred())
+	})
+	It("errors every item for 0 or negative n", func() {
+		_, errs := parallel.MapPartial([]int{1, 2}, 0, func(i int) (int, error) { return i, nil })
+		Expect(errs).To(ConsistOf(parallel.ErrInvalidParallelism, parallel.ErrInvalidParallelism))
+	})
 })
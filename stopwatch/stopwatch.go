@@ -6,6 +6,7 @@ then on success record the timing with Finish.
 It's recommended you do not record errors,
 since they can have vastly different timings
 (maybe we add an Error method in the future and record a different event).
+Measure times a function this way, logging a separate error event if it fails.
 */
 package stopwatch
 
@@ -19,6 +20,14 @@ type Stopwatch struct {
 	start     time.Time
 	operation string
 	logger    *slog.Logger
+	// phaseMark is when the current phase started; see Phase.
+	phaseMark time.Time
+	phases    []phase
+}
+
+type phase struct {
+	name    string
+	elapsed time.Duration
 }
 
 type StartOpts struct {
@@ -33,10 +42,12 @@ func StartWith(ctx context.Context, logger *slog.Logger, operation string, opts
 	if opts.Level == 0 {
 		opts.Level = slog.LevelDebug
 	}
+	now := time.Now()
 	sw := &Stopwatch{
-		start:     time.Now(),
+		start:     now,
 		operation: operation,
 		logger:    logger,
+		phaseMark: now,
 	}
 
 	sw.logger.Log(ctx, opts.Level, operation+opts.Key)
@@ -47,12 +58,47 @@ func Start(ctx context.Context, logger *slog.Logger, operation string) *Stopwatc
 	return StartWith(ctx, logger, operation, StartOpts{})
 }
 
+// Elapsed returns the time since the stopwatch was started.
+func (sw *Stopwatch) Elapsed() time.Duration {
+	return time.Since(sw.start)
+}
+
+// Phase records the time since the last call to Phase (or since the stopwatch started)
+// as the duration of the named phase. Calling Phase again with the same name adds to its duration.
+// Phases are logged together by Finish, as a "phases" group of phase name to milliseconds.
+// For example:
+//
+//	sw := stopwatch.Start(ctx, logger, "handle_request")
+//	user := authenticate()
+//	sw.Phase("auth")
+//	rows := query()
+//	sw.Phase("db")
+//	render(rows)
+//	sw.Phase("render")
+//	sw.Finish(ctx) // handle_request_finished elapsed=0.02 phases.auth=3 phases.db=12 phases.render=4
+func (sw *Stopwatch) Phase(name string) {
+	now := time.Now()
+	elapsed := now.Sub(sw.phaseMark)
+	sw.phaseMark = now
+	for i, p := range sw.phases {
+		if p.name == name {
+			sw.phases[i].elapsed += elapsed
+			return
+		}
+	}
+	sw.phases = append(sw.phases, phase{name: name, elapsed: elapsed})
+}
+
 type FinishOpts struct {
 	Logger       *slog.Logger
 	Key          string
 	ElapsedKey   string
 	Milliseconds bool
 	Level        slog.Level
+	// Label distinguishes multiple laps of the same operation,
+	// like "after_db" and "after_render".
+	// If set, it is logged as the "lap" attribute.
+	Label string
 }
 
 func (sw *Stopwatch) FinishWith(ctx context.Context, opts FinishOpts) {
@@ -69,11 +115,21 @@ func (sw *Stopwatch) FinishWith(ctx context.Context, opts FinishOpts) {
 		opts.Logger = sw.logger
 	}
 	logger := opts.Logger
+	if opts.Label != "" {
+		logger = logger.With("lap", opts.Label)
+	}
 	if opts.Milliseconds {
 		logger = logger.With(opts.ElapsedKey, time.Since(sw.start).Milliseconds())
 	} else {
 		logger = logger.With(opts.ElapsedKey, time.Since(sw.start).Seconds())
 	}
+	if len(sw.phases) > 0 {
+		attrs := make([]any, len(sw.phases))
+		for i, p := range sw.phases {
+			attrs[i] = slog.Int64(p.name, p.elapsed.Milliseconds())
+		}
+		logger = logger.With(slog.Group("phases", attrs...))
+	}
 	logger.Log(ctx, opts.Level, sw.operation+opts.Key)
 }
 
@@ -102,3 +158,23 @@ func (sw *Stopwatch) LapWith(ctx context.Context, opts LapOpts) {
 func (sw *Stopwatch) Lap(ctx context.Context) {
 	sw.LapWith(ctx, LapOpts{})
 }
+
+// Measure starts a stopwatch for operation, runs fn, and returns fn's error.
+// If fn succeeds, the stopwatch is finished normally.
+// If fn errors, the normal finish is not recorded, since error timings
+// are not comparable to successful ones (see package docs);
+// instead, an error-level operation+"_errored" message is logged
+// with the elapsed time and "error" attribute.
+func Measure(ctx context.Context, logger *slog.Logger, operation string, fn func() error) error {
+	sw := Start(ctx, logger, operation)
+	if err := fn(); err != nil {
+		sw.FinishWith(ctx, FinishOpts{
+			Logger: logger.With("error", err),
+			Key:    "_errored",
+			Level:  slog.LevelError,
+		})
+		return err
+	}
+	sw.Finish(ctx)
+	return nil
+}
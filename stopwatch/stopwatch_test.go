@@ -2,12 +2,14 @@ package stopwatch_test
 
 import (
 	"context"
+	"errors"
 	"github.com/lithictech/go-aperitif/v2/logctx"
 	"github.com/lithictech/go-aperitif/v2/stopwatch"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"log/slog"
 	"testing"
+	"time"
 )
 
 func TestStopwatch(t *testing.T) {
@@ -76,4 +78,66 @@ var _ = Describe("Stopwatch", func() {
 		Expect(hook.Records()[2].Record.Message).To(ContainSubstring("test_split"))
 		Expect(hook.Records()[2].AttrMap()).To(HaveKey("timing"))
 	})
+
+	It("can label laps", func() {
+		sw := stopwatch.Start(ctx, logger, "test")
+		sw.LapWith(ctx, stopwatch.LapOpts{Label: "after_db"})
+		sw.LapWith(ctx, stopwatch.LapOpts{Label: "after_render"})
+		Expect(hook.Records()).To(HaveLen(3))
+
+		Expect(hook.Records()[1].Record.Message).To(Equal("test_lap"))
+		Expect(hook.Records()[1].AttrMap()).To(HaveKeyWithValue("lap", "after_db"))
+		Expect(hook.Records()[2].Record.Message).To(Equal("test_lap"))
+		Expect(hook.Records()[2].AttrMap()).To(HaveKeyWithValue("lap", "after_render"))
+	})
+
+	It("can record phases", func() {
+		sw := stopwatch.Start(ctx, logger, "test")
+		time.Sleep(2 * time.Millisecond)
+		sw.Phase("db")
+		sw.Phase("render")
+		time.Sleep(2 * time.Millisecond)
+		sw.Phase("db")
+		sw.Finish(ctx)
+		Expect(hook.Records()).To(HaveLen(2))
+		phases := hook.Records()[1].AttrMap()["phases"]
+		Expect(phases).To(HaveLen(2))
+		Expect(phases).To(HaveExactElements(
+			And(HaveField("Key", "db"), HaveField("Value.Int64()", BeNumerically(">=", 4))),
+			HaveField("Key", "render"),
+		))
+	})
+
+	It("does not log phases if there are none", func() {
+		sw := stopwatch.Start(ctx, logger, "test")
+		sw.Finish(ctx)
+		Expect(hook.Records()[1].AttrMap()).ToNot(HaveKey("phases"))
+	})
+
+	Describe("Measure", func() {
+		It("times the function and returns nil if it succeeds", func() {
+			called := false
+			err := stopwatch.Measure(ctx, logger, "test", func() error {
+				called = true
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(called).To(BeTrue())
+			Expect(hook.Records()).To(HaveLen(2))
+			Expect(hook.Records()[1].Record.Message).To(Equal("test_finished"))
+			Expect(hook.Records()[1].AttrMap()).To(HaveKey("elapsed"))
+		})
+
+		It("logs an error instead of finishing, and returns the error, if the function errors", func() {
+			err := stopwatch.Measure(ctx, logger, "test", func() error {
+				return errors.New("oops")
+			})
+			Expect(err).To(MatchError("oops"))
+			Expect(hook.Records()).To(HaveLen(2))
+			Expect(hook.Records()[1].Record.Level).To(Equal(slog.LevelError))
+			Expect(hook.Records()[1].Record.Message).To(Equal("test_errored"))
+			Expect(hook.Records()[1].AttrMap()).To(HaveKey("elapsed"))
+			Expect(hook.Records()[1].AttrMap()).To(HaveKeyWithValue("error", MatchError("oops")))
+		})
+	})
 })
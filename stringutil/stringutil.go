@@ -0,0 +1,74 @@
+// Package stringutil has helpers for working with strings,
+// like for formatting them for logs.
+// Functions operate on runes, not bytes, so they never split a multibyte character.
+package stringutil
+
+import "strings"
+
+// EllipsisRune is appended to strings shortened by Ellipsis.
+const EllipsisRune = '…'
+
+// Truncate returns the first max runes of s.
+// If s has max or fewer runes, return it unchanged.
+func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	count := 0
+	for i := range s {
+		if count == max {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
+
+// Ellipsis shortens s to at most max runes, replacing the last rune with "…"
+// if s was shortened.
+// If s has max or fewer runes, return it unchanged.
+func Ellipsis(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	t := Truncate(s, max)
+	if t == s {
+		return s
+	}
+	return Truncate(t, max-1) + string(EllipsisRune)
+}
+
+// MaskRune replaces characters hidden by MaskMiddle and MaskEmail.
+const MaskRune = '*'
+
+// MaskMiddle replaces all but the first keepStart and last keepEnd runes of s
+// with MaskRune, like "sk_live_********abcd".
+// If s is not longer than keepStart+keepEnd, all of it is masked,
+// so short values are never fully revealed.
+func MaskMiddle(s string, keepStart, keepEnd int) string {
+	runes := []rune(s)
+	if keepStart < 0 {
+		keepStart = 0
+	}
+	if keepEnd < 0 {
+		keepEnd = 0
+	}
+	if len(runes) <= keepStart+keepEnd {
+		keepStart, keepEnd = 0, 0
+	}
+	for i := keepStart; i < len(runes)-keepEnd; i++ {
+		runes[i] = MaskRune
+	}
+	return string(runes)
+}
+
+// MaskEmail masks all but the first rune of the local part of an email address,
+// like "j***@example.com". The domain is left unchanged.
+// If s has no "@", it is fully masked.
+func MaskEmail(s string) string {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return MaskMiddle(s, 0, 0)
+	}
+	return MaskMiddle(s[:at], 1, 0) + s[at:]
+}
@@ -0,0 +1,71 @@
+package stringutil_test
+
+import (
+	"github.com/lithictech/go-aperitif/v2/stringutil"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStringutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "stringutil package Suite")
+}
+
+var _ = Describe("stringutil", func() {
+	Describe("Truncate", func() {
+		It("returns the first n runes", func() {
+			Expect(stringutil.Truncate("hello", 3)).To(Equal("hel"))
+			Expect(stringutil.Truncate("hello", 5)).To(Equal("hello"))
+			Expect(stringutil.Truncate("hello", 10)).To(Equal("hello"))
+			Expect(stringutil.Truncate("hello", 0)).To(Equal(""))
+			Expect(stringutil.Truncate("", 3)).To(Equal(""))
+		})
+		It("does not cut multibyte characters", func() {
+			s := stringutil.Truncate("héllo wörld", 2)
+			Expect(s).To(Equal("hé"))
+			Expect(utf8.ValidString(s)).To(BeTrue())
+			Expect(stringutil.Truncate("日本語テキスト", 3)).To(Equal("日本語"))
+		})
+	})
+	Describe("Ellipsis", func() {
+		It("shortens and appends an ellipsis", func() {
+			Expect(stringutil.Ellipsis("hello", 4)).To(Equal("hel…"))
+			Expect(stringutil.Ellipsis("hello", 5)).To(Equal("hello"))
+			Expect(stringutil.Ellipsis("hello", 1)).To(Equal("…"))
+			Expect(stringutil.Ellipsis("hello", 0)).To(Equal(""))
+		})
+		It("does not cut multibyte characters", func() {
+			s := stringutil.Ellipsis("日本語テキスト", 4)
+			Expect(s).To(Equal("日本語…"))
+			Expect(utf8.RuneCountInString(s)).To(Equal(4))
+			Expect(utf8.ValidString(s)).To(BeTrue())
+		})
+	})
+	Describe("MaskMiddle", func() {
+		It("masks all but the start and end", func() {
+			Expect(stringutil.MaskMiddle("sk_live_12345678abcd", 8, 4)).To(Equal("sk_live_********abcd"))
+			Expect(stringutil.MaskMiddle("secret", 0, 2)).To(Equal("****et"))
+			Expect(stringutil.MaskMiddle("secret", 2, 0)).To(Equal("se****"))
+		})
+		It("fully masks strings that are too short", func() {
+			Expect(stringutil.MaskMiddle("abcd", 2, 2)).To(Equal("****"))
+			Expect(stringutil.MaskMiddle("abc", 2, 2)).To(Equal("***"))
+			Expect(stringutil.MaskMiddle("", 2, 2)).To(Equal(""))
+		})
+		It("masks runes, not bytes", func() {
+			Expect(stringutil.MaskMiddle("日本語テキスト", 1, 1)).To(Equal("日*****ト"))
+		})
+	})
+	Describe("MaskEmail", func() {
+		It("masks the local part of an email", func() {
+			Expect(stringutil.MaskEmail("jane@example.com")).To(Equal("j***@example.com"))
+			Expect(stringutil.MaskEmail("j@example.com")).To(Equal("*@example.com"))
+			Expect(stringutil.MaskEmail("@example.com")).To(Equal("@example.com"))
+		})
+		It("fully masks strings that are not emails", func() {
+			Expect(stringutil.MaskEmail("jane")).To(Equal("****"))
+		})
+	})
+})
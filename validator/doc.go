@@ -54,6 +54,18 @@ Available validators include:
 		(validation will only be done if a value is provided).
 		(Usage: uuid4 uuid4=opt)
 
+	ulid
+		For string types, validate that the string is a ULID:
+		26 characters of Crockford base32, in upper or lower case.
+		If "opt" is specified, an empty string is accepted.
+		(Usage: ulid ulid=opt)
+
+	base32
+		For string types, validate that the string is base32
+		using the standard (RFC 4648) alphabet, with or without padding.
+		If "opt" is specified, an empty string is accepted.
+		(Usage: base32 base32=opt)
+
 	url
 		For string types, validate that the string is parseable as
 		a request URI via net/url.ParseRequestURI.
@@ -62,11 +74,20 @@ Available validators include:
 		If "opt" is specified, an empty string is accepted.
 		(Usage: url url=opt)
 
+	email
+		For string types, validate that the string is an email address
+		parseable via net/mail.ParseAddress.
+		Only the bare address is accepted, so "Name <a@b.c>" is invalid.
+		If "opt" is specified, an empty string is accepted.
+		(Usage: email email=opt)
+
 	enum
 		For string types, validate that the string is one of the specified choices.
 		Choices should be pipe-delimited. Matching is case-insensitive.
 		If "|opt" is the trailing argument, treat the value as optional
 		(an empty string is valid).
+		Use NormalizeEnums to rewrite matched values to the casing of the choice
+		(apiparams does this after validating).
 
 		For string slices, validate that each member is one of the specified choices.
 		"|opt" cannot be used for string slices, since it is ambiguous in two ways.
@@ -80,6 +101,12 @@ Available validators include:
 		Same as enum validator, but comparison is case-sensitive.
 		(Usage: cenum=bird|shark|whale cenum=bird|shark|whale|opt)
 
+	inset
+		For numeric types (ints, uints, and floats), validate that the value
+		is one of the specified numbers. Numbers should be pipe-delimited.
+		If "|opt" is the trailing argument, the zero value is valid.
+		(Usage: inset=1|2|5 inset=1|2|5|opt)
+
 	comparenow
 		For time.Time types, validate the time relative to
 		the time unit the current moment is in.
@@ -91,6 +118,13 @@ Available validators include:
 		(validation will only be done if a value is provided).
 		(Usage: comparenow=hour|gte comparenow=day|lt|opt)
 
+	notempty
+		For strings, slices, arrays, and maps, validate that the value
+		has at least one character or item. For pointers, validate that
+		the pointer is not nil, and the value it points to is not empty.
+		Prefer this to min=1, which has a less clear error message.
+		(Usage: notempty)
+
 # Optional validations
 
 Most validators support a way to specify they are optional.
@@ -118,5 +152,25 @@ However, a nil pointer is not acceptable here, because of the "nonzero" validati
 	type d struct {
 	    D *time.Time `json:"d" validate:"comparenow=lte|day,nonzero"`
 	}
+
+# Custom validators
+
+Project-specific validators can be added with RegisterValidationFunc,
+and then used in struct tags like the built-in validators.
+Registration is not goroutine-safe, so do it at init time:
+
+	func init() {
+	    validator.RegisterValidationFunc("iseven", func(v interface{}, param string) error {
+	        if i, ok := v.(int); ok && i%2 != 0 {
+	            return errors.New("not even")
+	        }
+	        return nil
+	    })
+	}
+
+Use AssertKnownTags in tests to make sure every validate tag on a struct
+uses a registered validator, to catch typos like `validate:"uuid"`:
+
+	Expect(validator.AssertKnownTags(&CreateUserParams{})).To(Succeed())
 */
 package validator
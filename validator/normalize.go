@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+)
+
+// NormalizeEnums rewrites the values of string fields validated with enum
+// to the casing of the matching choice, so a value of "BIRD" for `validate:"enum=bird|shark"`
+// becomes "bird". This works for string, *string, []string, and *[]string fields,
+// and recurses into nested structs, structs in slices, and embedded structs, like RulesFor.
+// Values that do not match a choice are left alone;
+// call NormalizeEnums after the struct passes validation.
+//
+// ptr must be a pointer to a struct; other values are ignored.
+func NormalizeEnums(ptr interface{}) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	normalizeValue(v.Elem())
+}
+
+func normalizeValue(v reflect.Value) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		normalizeStruct(v)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			normalizeValue(v.Index(i))
+		}
+	}
+}
+
+func normalizeStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		if choices, ok := enumChoices(f.Tag.Get("validate")); ok && fv.CanSet() {
+			normalizeEnumValue(fv, choices)
+			continue
+		}
+		normalizeValue(fv)
+	}
+}
+
+// enumChoices returns the choices of the enum rule in a validate tag,
+// and true if there is one.
+func enumChoices(tag string) ([]string, bool) {
+	for _, rule := range strings.Split(tag, ",") {
+		param, ok := strings.CutPrefix(strings.TrimSpace(rule), "enum=")
+		if !ok {
+			continue
+		}
+		choices, _, err := splitOptionalVal(param)
+		return choices, err == nil
+	}
+	return nil, false
+}
+
+func normalizeEnumValue(v reflect.Value, choices []string) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if choice, ok := canonicalChoice(v.String(), choices); ok {
+			v.SetString(choice)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			normalizeEnumValue(v.Index(i), choices)
+		}
+	}
+}
+
+func canonicalChoice(s string, choices []string) (string, bool) {
+	// Compare like the enum validator does.
+	lower := strings.ToLower(s)
+	for _, choice := range choices {
+		if strings.ToLower(choice) == lower {
+			return choice, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RulesFor returns the `validate` tag of each exported field of the struct
+// (or pointer to a struct) v, keyed by field path.
+// Fields without a tag are included with an empty string,
+// so callers can find unvalidated fields.
+//
+// Like apiparams, RulesFor recurses into nested structs (keyed like "Nest.B"),
+// structs in slices (keyed like "Items.Qty", without an index),
+// and flattens anonymous embedded structs into the outer struct.
+//
+// This is useful for tests that enforce conventions, like every string field having a max length:
+//
+//	for field, rule := range validator.RulesFor(&CreateUserParams{}) {
+//		Expect(rule).To(ContainSubstring("max="), field)
+//	}
+func RulesFor(v interface{}) map[string]string {
+	result := make(map[string]string)
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return result
+	}
+	collectRules(t, "", result)
+	return result
+}
+
+func collectRules(t reflect.Type, prefix string, result map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			collectRules(ft, prefix, result)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		path := prefix + f.Name
+		result[path] = f.Tag.Get("validate")
+		if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft = ft.Elem()
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+		}
+		if ft.Kind() == reflect.Struct {
+			collectRules(ft, path+".", result)
+		}
+	}
+}
+
+// AssertKnownTags returns an ErrorMap, keyed by field path like RulesFor,
+// if any validate tag on the struct (or pointer to a struct) v uses a validator
+// that is not registered on the global registry, like `validate:"uuid"` rather than `validate:"uuid4"`.
+// Each error is an ErrUnknownValidator, and has the unknown name as its ValidatorName.
+// It returns nil if all the validators are known.
+//
+// Since a field with an unknown validator fails to validate only when it is validated,
+// use this in unit tests to catch typos in tags:
+//
+//	Expect(validator.AssertKnownTags(&CreateUserParams{})).To(Succeed())
+//
+// Make sure custom validators are registered (see RegisterValidationFunc) before calling it.
+func AssertKnownTags(v interface{}) error {
+	return globalRegistry.AssertKnownTags(v)
+}
+
+// AssertKnownTags is like the package-level AssertKnownTags,
+// using the validators registered on r.
+func (r *Registry) AssertKnownTags(v interface{}) error {
+	errs := make(ErrorMap)
+	for path, rule := range RulesFor(v) {
+		for _, name := range validatorNames(rule) {
+			if !r.names[name] {
+				errs[path] = append(errs[path], ValidatorError{
+					Validator: name,
+					Err:       fmt.Errorf("%w %q", ErrUnknownValidator, name),
+				})
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Rules are separated by commas, except for commas escaped with a backslash.
+// This matches how go-validator parses tags.
+var ruleSepPattern = regexp.MustCompile(`((?:^|[^\\])(?:\\\\)*),`)
+
+// validatorNames returns the name of each validator in a validate tag,
+// like ["min", "max"] for "min=1,max=5".
+func validatorNames(tag string) []string {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+	var names []string
+	last := 0
+	addRule := func(rule string) {
+		name, _, _ := strings.Cut(rule, "=")
+		names = append(names, strings.TrimSpace(name))
+	}
+	for _, idx := range ruleSepPattern.FindAllStringIndex(tag, -1) {
+		addRule(tag[last : idx[1]-1])
+		last = idx[1]
+	}
+	addRule(tag[last:])
+	return names
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -22,6 +23,21 @@ func (err ErrorMap) Error() string {
 	return strings.Join(lines, " | ")
 }
 
+// ToStructured returns the messages for each field,
+// for serializing validation errors (like in an API response)
+// without parsing the output of Error.
+func (err ErrorMap) ToStructured() map[string][]string {
+	result := make(map[string][]string, len(err))
+	for k, errs := range err {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Error())
+		}
+		result[k] = messages
+	}
+	return result
+}
+
 // ErrorArray is a slice of errors returned by the Validate function.
 type ErrorArray []error
 
@@ -35,26 +51,138 @@ func (err ErrorArray) Error() string {
 	return strings.Join(errs, ", ")
 }
 
+// ValidatorError is an error from a single named validator, like "len" or "intid".
+// The errors in an ErrorArray returned from Validate are ValidatorErrors,
+// so callers can tell which validator failed (for example, to localize messages).
+//
+// Because errors are wrapped, compare them with errors.Is(err, ErrInvalidURL)
+// rather than err == ErrInvalidURL.
+// ValidatorError marshals to its message, so an ErrorMap still serializes
+// to JSON like {"Field": ["not a valid url"]}.
+type ValidatorError struct {
+	// Validator is the name of the validator that failed, as used in the struct tag.
+	// It is empty if the validator could not be determined.
+	Validator string
+	// Err is the underlying error, like ErrInvalidIntID.
+	Err error
+}
+
+// Error returns the underlying error message, so messages are the same
+// as if the ValidatorError was not used.
+func (e ValidatorError) Error() string {
+	return e.Err.Error()
+}
+
+// MarshalText returns the underlying error message, like TextErr does.
+func (e ValidatorError) MarshalText() ([]byte, error) {
+	return []byte(e.Err.Error()), nil
+}
+
+func (e ValidatorError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatorName returns the name of the validator that caused err,
+// or an empty string if err is not a ValidatorError.
+func ValidatorName(err error) string {
+	var ve ValidatorError
+	if errors.As(err, &ve) {
+		return ve.Validator
+	}
+	return ""
+}
+
+// builtinValidatorNames maps the errors from go-validator's builtin validators
+// to the name of the validator. We cannot wrap the builtin validation functions
+// like we do our own, since go-validator does not expose them.
+var builtinValidatorNames = map[error]string{
+	validator.ErrZeroValue: "nonzero",
+	validator.ErrLen:       "len",
+	validator.ErrMin:       "min",
+	validator.ErrMax:       "max",
+	validator.ErrRegexp:    "regexp",
+}
+
 // Registry is a registry of all available validation functions.
 // It must be initialized before using.
 // In general, clients should use the global instance available through
 // the Validate function; instances are generally only used for testing.
 type Registry struct {
 	validator *validator.Validator
+	// names is the set of registered validator names, for AssertKnownTags.
+	names map[string]bool
 }
 
 type nowSource func() time.Time
 
 // Init initializes a registry (registers all validators).
 func (r *Registry) Init(getNow nowSource) {
-	v := validator.NewValidator()
-	v.SetValidationFunc("intid", validateIntID)
-	v.SetValidationFunc("uuid4", validateUUID4)
-	v.SetValidationFunc("url", validateURL)
-	v.SetValidationFunc("enum", validateCaseInsensitiveEnum)
-	v.SetValidationFunc("cenum", validateCaseSensitiveEnum)
-	v.SetValidationFunc("comparenow", makeValidateCompareNow(getNow))
-	r.validator = v
+	r.validator = validator.NewValidator()
+	r.names = make(map[string]bool)
+	for _, name := range builtinValidatorNames {
+		r.names[name] = true
+	}
+	r.RegisterValidationFunc("intid", validateIntID)
+	r.RegisterValidationFunc("uuid4", validateUUID4)
+	r.RegisterValidationFunc("ulid", validateULID)
+	r.RegisterValidationFunc("base32", validateBase32)
+	r.RegisterValidationFunc("url", validateURL)
+	r.RegisterValidationFunc("email", validateEmail)
+	r.RegisterValidationFunc("enum", validateCaseInsensitiveEnum)
+	r.RegisterValidationFunc("cenum", validateCaseSensitiveEnum)
+	r.RegisterValidationFunc("inset", validateInSet)
+	r.RegisterValidationFunc("comparenow", makeValidateCompareNow(getNow))
+	r.RegisterValidationFunc("notempty", validateNotEmpty)
+	for _, c := range customValidationFuncs {
+		r.RegisterValidationFunc(c.name, c.fn)
+	}
+}
+
+// ValidationFunc is a function that validates a field value.
+// v is the field value, and param is the validator parameter from the struct tag
+// (like "5" for `validate:"min=5"`).
+// Return nil if the value is valid, or an error describing why it is not.
+// Return an error like validator.ErrUnsupported from github.com/rgalanakis/validator
+// if the validator does not support the type of v.
+type ValidationFunc = validator.ValidationFunc
+
+// RegisterValidationFunc registers fn as the validator named name on this Registry,
+// replacing any existing validator with that name.
+// Registration is not goroutine-safe, and should be done before the Registry is used.
+func (r *Registry) RegisterValidationFunc(name string, fn ValidationFunc) {
+	setNamedValidationFunc(r.validator, name, fn)
+	r.names[name] = true
+}
+
+type namedValidationFunc struct {
+	name string
+	fn   ValidationFunc
+}
+
+var customValidationFuncs []namedValidationFunc
+
+// RegisterValidationFunc registers fn as the validator named name,
+// so it can be used by Validate (and apiparams),
+// and by Registries created afterwards with NewRegistry.
+// Registration is not goroutine-safe, so should be done at init time, like:
+//
+//	func init() {
+//		validator.RegisterValidationFunc("iseven", func(v interface{}, param string) error { ... })
+//	}
+func RegisterValidationFunc(name string, fn ValidationFunc) {
+	customValidationFuncs = append(customValidationFuncs, namedValidationFunc{name, fn})
+	globalRegistry.RegisterValidationFunc(name, fn)
+}
+
+// setNamedValidationFunc registers fn as name, wrapping any error it returns
+// in a ValidatorError so we know which validator failed.
+func setNamedValidationFunc(v *validator.Validator, name string, fn validator.ValidationFunc) {
+	v.SetValidationFunc(name, func(value interface{}, param string) error {
+		if err := fn(value, param); err != nil {
+			return ValidatorError{Validator: name, Err: err}
+		}
+		return nil
+	})
 }
 
 // Validate validates using all registered validators.
@@ -109,7 +237,23 @@ func coerceValidatorPkgErrorMap(err validator.ErrorMap) ErrorMap {
 func coerceValidatorPkgErrorArray(err validator.ErrorArray) ErrorArray {
 	result := make(ErrorArray, 0, len(err))
 	for _, e := range err {
-		result = append(result, e)
+		result = append(result, coerceValidatorPkgErrorElement(e))
 	}
 	return result
 }
+
+// coerceValidatorPkgErrorElement returns err as a ValidatorError,
+// using the builtin validator name if err came from a go-validator builtin.
+func coerceValidatorPkgErrorElement(err error) error {
+	if _, ok := err.(ValidatorError); ok {
+		return err
+	}
+	ve := ValidatorError{Err: err}
+	for sentinel, name := range builtinValidatorNames {
+		if errors.Is(err, sentinel) {
+			ve.Validator = name
+			break
+		}
+	}
+	return ve
+}
@@ -1,6 +1,7 @@
 package validator_test
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/lithictech/go-aperitif/v2/validator"
 	. "github.com/onsi/ginkgo/v2"
@@ -51,6 +52,217 @@ var _ = Describe("Validator", func() {
 		expectInvalid(t{4}, "I", "less than min")
 	})
 
+	Describe("ValidatorName", func() {
+		It("returns the name of the validator that failed", func() {
+			type t struct {
+				I  int    `validate:"min=5"`
+				ID string `validate:"intid"`
+			}
+			errMap := registry.Validate(t{ID: "x"}).(validator.ErrorMap)
+			Expect(validator.ValidatorName(errMap["I"][0])).To(Equal("min"))
+			Expect(validator.ValidatorName(errMap["ID"][0])).To(Equal("intid"))
+			Expect(errors.Is(errMap["ID"][0], validator.ErrInvalidIntID)).To(BeTrue())
+		})
+		It("returns an empty string for other errors", func() {
+			Expect(validator.ValidatorName(errors.New("x"))).To(BeEmpty())
+		})
+		It("still marshals the ErrorMap to JSON messages", func() {
+			type t struct {
+				I   int    `validate:"min=5"`
+				URL string `validate:"url"`
+			}
+			b, err := json.Marshal(registry.Validate(t{URL: "x y"}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(MatchJSON(`{"I":["less than min"],"URL":["not a valid url"]}`))
+			var roundTripped map[string][]string
+			Expect(json.Unmarshal(b, &roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(map[string][]string{"I": {"less than min"}, "URL": {"not a valid url"}}))
+		})
+	})
+
+	Describe("RegisterValidationFunc", func() {
+		isEven := func(v interface{}, param string) error {
+			i, ok := v.(int)
+			if !ok {
+				return errors.New("unsupported type")
+			}
+			if i%2 != 0 {
+				return errors.New("not even")
+			}
+			return nil
+		}
+		type t struct {
+			I int `validate:"iseven"`
+		}
+
+		It("registers a validator on the global registry", func() {
+			validator.RegisterValidationFunc("iseven", isEven)
+			Expect(validator.Validate(t{2})).To(Succeed())
+			err := validator.Validate(t{3})
+			Expect(err).To(HaveOccurred())
+			errMap := err.(validator.ErrorMap)
+			Expect(errMap["I"][0].Error()).To(Equal("not even"))
+			Expect(validator.ValidatorName(errMap["I"][0])).To(Equal("iseven"))
+			// Registries created later have the validator too.
+			Expect(validator.NewRegistry(time.Now).Validate(t{3})).To(HaveOccurred())
+		})
+
+		It("registers a validator on a registry", func() {
+			registry.RegisterValidationFunc("iseven2", isEven)
+			type t2 struct {
+				I int `validate:"iseven2"`
+			}
+			expectValid(t2{2})
+			expectInvalid(t2{3}, "I", "not even")
+		})
+	})
+
+	Describe("AssertKnownTags", func() {
+		It("succeeds if all validators are registered", func() {
+			type nested struct {
+				ID string `validate:"uuid4"`
+			}
+			type t struct {
+				I      int    `validate:"min=1,max=5"`
+				S      string `validate:"regexp=^a\\,b$,nonzero"`
+				E      string `validate:"enum=a|b"`
+				Skip   string `validate:"-"`
+				None   string
+				Nested nested
+			}
+			Expect(registry.AssertKnownTags(&t{})).To(Succeed())
+			Expect(validator.AssertKnownTags(t{})).To(Succeed())
+		})
+
+		It("returns an error for unknown validators, including nested and embedded fields", func() {
+			type Embedded struct {
+				E string `validate:"emial"`
+			}
+			type nested struct {
+				ID string `validate:"uuid"`
+			}
+			type t struct {
+				Embedded
+				I      int `validate:"min=1,maxx=5"`
+				Nested *nested
+				Items  []nested
+			}
+			err := registry.AssertKnownTags(&t{})
+			Expect(err).To(HaveOccurred())
+			errMap := err.(validator.ErrorMap)
+			Expect(errMap.ToStructured()).To(Equal(map[string][]string{
+				"E":         {`unknown validator "emial"`},
+				"I":         {`unknown validator "maxx"`},
+				"Nested.ID": {`unknown validator "uuid"`},
+				"Items.ID":  {`unknown validator "uuid"`},
+			}))
+			Expect(errors.Is(errMap["I"][0], validator.ErrUnknownValidator)).To(BeTrue())
+			Expect(validator.ValidatorName(errMap["I"][0])).To(Equal("maxx"))
+		})
+
+		It("knows about registered custom validators", func() {
+			type t struct {
+				I int `validate:"isodd"`
+			}
+			Expect(registry.AssertKnownTags(&t{})).To(HaveOccurred())
+			registry.RegisterValidationFunc("isodd", func(interface{}, string) error { return nil })
+			Expect(registry.AssertKnownTags(&t{})).To(Succeed())
+		})
+	})
+
+	Describe("NormalizeEnums", func() {
+		It("rewrites enum values to the casing of the choice", func() {
+			type nested struct {
+				Kind string `validate:"enum=Bird|Shark"`
+			}
+			type embedded struct {
+				Color string `validate:"enum=red|blue"`
+			}
+			type s struct {
+				embedded
+				Animal   string   `validate:"enum=bird|shark|opt"`
+				Ptr      *string  `validate:"min=1,enum=bird|shark"`
+				NilPtr   *string  `validate:"enum=bird|shark"`
+				Slice    []string `validate:"enum=bird|shark"`
+				Cenum    string   `validate:"cenum=bird|shark"`
+				Other    string   `validate:"enum=bird|shark"`
+				Nested   nested
+				Children []nested
+			}
+			ptr := "SHARK"
+			v := s{
+				embedded: embedded{"RED"},
+				Animal:   "BIRD",
+				Ptr:      &ptr,
+				Slice:    []string{"Bird", "shark"},
+				Cenum:    "BIRD",
+				Other:    "whale",
+				Nested:   nested{"bird"},
+				Children: []nested{{"SHARK"}},
+			}
+			validator.NormalizeEnums(&v)
+			Expect(v.Color).To(Equal("red"))
+			Expect(v.Animal).To(Equal("bird"))
+			Expect(*v.Ptr).To(Equal("shark"))
+			Expect(v.NilPtr).To(BeNil())
+			Expect(v.Slice).To(Equal([]string{"bird", "shark"}))
+			Expect(v.Cenum).To(Equal("BIRD"))
+			Expect(v.Other).To(Equal("whale"))
+			Expect(v.Nested.Kind).To(Equal("Bird"))
+			Expect(v.Children[0].Kind).To(Equal("Shark"))
+		})
+
+		It("ignores values that are not struct pointers", func() {
+			type s struct {
+				Animal string `validate:"enum=bird"`
+			}
+			Expect(func() {
+				validator.NormalizeEnums(s{"BIRD"})
+				validator.NormalizeEnums(nil)
+				validator.NormalizeEnums((*s)(nil))
+			}).ToNot(Panic())
+		})
+	})
+
+	Describe("RulesFor", func() {
+		It("returns the validate tag for each field", func() {
+			type item struct {
+				Qty int `validate:"min=1"`
+			}
+			type base struct {
+				ID int `validate:"intid"`
+			}
+			type params struct {
+				base
+				Name   string `validate:"max=10"`
+				NoTag  string
+				Nested struct {
+					B string `validate:"nonzero"`
+				}
+				Items      []item
+				Ptr        *item `validate:"nonzero"`
+				When       time.Time
+				unexported string
+			}
+			Expect(validator.RulesFor(&params{})).To(Equal(map[string]string{
+				"ID":        "intid",
+				"Name":      "max=10",
+				"NoTag":     "",
+				"Nested":    "",
+				"Nested.B":  "nonzero",
+				"Items":     "",
+				"Items.Qty": "min=1",
+				"Ptr":       "nonzero",
+				"Ptr.Qty":   "min=1",
+				"When":      "",
+			}))
+		})
+		It("returns an empty map for non-structs", func() {
+			Expect(validator.RulesFor(5)).To(BeEmpty())
+			Expect(validator.RulesFor(nil)).To(BeEmpty())
+		})
+	})
+
 	Describe("ErrorMap", func() {
 
 		It("renders all errors in its Error()", func() {
@@ -66,6 +278,19 @@ var _ = Describe("Validator", func() {
 		})
 	})
 
+	Describe("ErrorMap.ToStructured", func() {
+		It("returns the messages for each field", func() {
+			e := validator.ErrorMap{
+				"Abc": validator.ErrorArray{errors.New("err1"), errors.New("err2")},
+				"Xyz": validator.ErrorArray{errors.New("err3")},
+			}
+			Expect(e.ToStructured()).To(Equal(map[string][]string{
+				"Abc": {"err1", "err2"},
+				"Xyz": {"err3"},
+			}))
+		})
+	})
+
 	Describe("ErrorArray", func() {
 
 		It("renders all errors in its Error()", func() {
@@ -220,6 +445,36 @@ var _ = Describe("Validator", func() {
 		})
 	})
 
+	Describe("notempty", func() {
+		It("requires a non-empty string, slice, or map", func() {
+			type d struct {
+				S string            `validate:"notempty"`
+				L []int             `validate:"notempty"`
+				M map[string]string `validate:"notempty"`
+			}
+			expectValid(d{"a", []int{1}, map[string]string{"a": "b"}})
+			expectInvalid(d{"", []int{1}, map[string]string{"a": "b"}}, "S", "must not be empty")
+			expectInvalid(d{"a", []int{}, map[string]string{"a": "b"}}, "L", "must not be empty")
+			expectInvalid(d{"a", []int{1}, nil}, "M", "must not be empty")
+		})
+
+		It("requires pointers to be non-nil and point to a non-empty value", func() {
+			type d struct {
+				V *[]string `validate:"notempty"`
+			}
+			expectInvalid(d{nil}, "V", "must not be empty")
+			expectInvalid(d{&[]string{}}, "V", "must not be empty")
+			expectValid(d{&[]string{"a"}})
+		})
+
+		It("is unsupported for other types", func() {
+			type d struct {
+				V int `validate:"notempty"`
+			}
+			expectInvalid(d{1}, "V", "unsupported type")
+		})
+	})
+
 	Describe("enum", func() {
 		It("requires a case-insensitive choice from a list of strings", func() {
 			type d struct {
@@ -350,6 +605,141 @@ var _ = Describe("Validator", func() {
 		})
 	})
 
+	Describe("inset", func() {
+		It("requires the value to be one of the numbers", func() {
+			type s struct {
+				Status int `json:"status" validate:"inset=1|2|5"`
+			}
+			expectValid(s{1})
+			expectValid(s{5})
+			expectInvalid(s{3}, "Status", "is not one of 1|2|5")
+			expectInvalid(s{0}, "Status", "is not one of 1|2|5")
+		})
+
+		It("works with other numeric types", func() {
+			type s struct {
+				I64 int64   `json:"i64" validate:"inset=1|2"`
+				I32 int32   `json:"i32" validate:"inset=1|2"`
+				F   float64 `json:"f" validate:"inset=1.5|2"`
+			}
+			expectValid(s{1, 2, 1.5})
+			expectInvalid(s{1, 2, 1}, "F", "is not one of 1.5|2")
+			expectInvalid(s{3, 2, 2}, "I64", "is not one of 1|2")
+		})
+
+		It("can specify it is optional (zero value is valid)", func() {
+			type s struct {
+				Status int `json:"status" validate:"inset=1|2|5|opt"`
+			}
+			expectValid(s{0})
+			expectValid(s{2})
+			expectInvalid(s{3}, "Status", "is not one of 1|2|5")
+		})
+
+		It("can validate pointer fields", func() {
+			type s struct {
+				Status *int `json:"status" validate:"inset=1|2|5"`
+			}
+			expectValid(s{nil})
+			valid := 2
+			expectValid(s{&valid})
+			invalid := 3
+			expectInvalid(s{&invalid}, "Status", "is not one of 1|2|5")
+		})
+
+		It("errors for invalid parameters and unsupported types", func() {
+			type badParam struct {
+				Status int `validate:"inset=a|b"`
+			}
+			expectInvalid(badParam{1}, "Status", "bad parameter")
+			type unsupported struct {
+				Status string `validate:"inset=1|2"`
+			}
+			expectInvalid(unsupported{"1"}, "Status", "unsupported type")
+		})
+	})
+
+	Describe("ulid", func() {
+		It("requires a ULID", func() {
+			type s struct {
+				ID string `json:"id" validate:"ulid"`
+			}
+			expectValid(s{"01ARZ3NDEKTSV4RRFFQ69G5FAV"})
+			expectValid(s{"01arz3ndektsv4rrffq69g5fav"})
+			expectInvalid(s{"01ARZ3NDEKTSV4RRFFQ69G5FA"}, "ID", "not a ulid string")
+			expectInvalid(s{"01ARZ3NDEKTSV4RRFFQ69G5FAVX"}, "ID", "not a ulid string")
+			expectInvalid(s{"01ARZ3NDEKTSV4RRFFQ69G5FAU"}, "ID", "not a ulid string")
+			expectInvalid(s{"81ARZ3NDEKTSV4RRFFQ69G5FAV"}, "ID", "not a ulid string")
+			expectInvalid(s{""}, "ID", "not a ulid string")
+		})
+
+		It("can specify it is optional (empty string is valid)", func() {
+			type s struct {
+				ID string `json:"id" validate:"ulid=opt"`
+			}
+			expectValid(s{""})
+			expectInvalid(s{"abc"}, "ID", "not a ulid string")
+		})
+
+		It("can validate pointer fields", func() {
+			type s struct {
+				ID *string `json:"id" validate:"ulid"`
+			}
+			expectValid(s{nil})
+			valid := "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+			expectValid(s{&valid})
+			invalid := "abc"
+			expectInvalid(s{&invalid}, "ID", "not a ulid string")
+		})
+
+		It("returns ErrInvalidULID", func() {
+			type s struct {
+				ID string `json:"id" validate:"ulid"`
+			}
+			errMap := registry.Validate(s{"abc"}).(validator.ErrorMap)
+			Expect(errors.Is(errMap["ID"][0], validator.ErrInvalidULID)).To(BeTrue())
+		})
+	})
+
+	Describe("base32", func() {
+		It("requires base32", func() {
+			type s struct {
+				V string `json:"v" validate:"base32"`
+			}
+			expectValid(s{"MZXW6YTBOI======"})
+			expectValid(s{"MZXW6YTBOI"})
+			expectInvalid(s{"MZXW6YTBOI==="}, "V", "not a base32 string")
+			expectInvalid(s{"mzxw6ytboi"}, "V", "not a base32 string")
+			expectInvalid(s{"MZXW1"}, "V", "not a base32 string")
+			expectInvalid(s{""}, "V", "not a base32 string")
+		})
+
+		It("can specify it is optional (empty string is valid)", func() {
+			type s struct {
+				V string `json:"v" validate:"base32=opt"`
+			}
+			expectValid(s{""})
+			expectInvalid(s{"1"}, "V", "not a base32 string")
+		})
+
+		It("can validate pointer fields", func() {
+			type s struct {
+				V *string `json:"v" validate:"base32"`
+			}
+			expectValid(s{nil})
+			invalid := "1"
+			expectInvalid(s{&invalid}, "V", "not a base32 string")
+		})
+
+		It("returns ErrInvalidBase32", func() {
+			type s struct {
+				V string `json:"v" validate:"base32"`
+			}
+			errMap := registry.Validate(s{"1"}).(validator.ErrorMap)
+			Expect(errors.Is(errMap["V"][0], validator.ErrInvalidBase32)).To(BeTrue())
+		})
+	})
+
 	Describe("url", func() {
 		It("requires a parse-able URL", func() {
 			type s struct {
@@ -383,4 +773,48 @@ var _ = Describe("Validator", func() {
 			expectValid(s{&valid})
 		})
 	})
+
+	Describe("email", func() {
+		It("requires a bare email address", func() {
+			type s struct {
+				Email string `json:"email" validate:"email"`
+			}
+			expectInvalid(s{"foo.com"}, "Email", "not a valid email")
+			expectInvalid(s{""}, "Email", "not a valid email")
+			expectInvalid(s{"Foo <foo@bar.com>"}, "Email", "not a valid email")
+			expectInvalid(s{"<foo@bar.com>"}, "Email", "not a valid email")
+			expectValid(s{"foo@bar.com"})
+			expectValid(s{"foo+baz@bar.co.uk"})
+		})
+
+		It("can specify it is optional (empty string is valid)", func() {
+			type s struct {
+				Email string `json:"email" validate:"email=opt"`
+			}
+			expectInvalid(s{"foo.com"}, "Email", "not a valid email")
+			expectValid(s{""})
+			expectValid(s{"foo@bar.com"})
+		})
+
+		It("can validate pointer fields", func() {
+			type s struct {
+				Email *string `json:"email" validate:"email"`
+			}
+			expectValid(s{nil})
+			invalid := "foo.com"
+			expectInvalid(s{&invalid}, "Email", "not a valid email")
+			empty := ""
+			expectInvalid(s{&empty}, "Email", "not a valid email")
+			valid := "foo@bar.com"
+			expectValid(s{&valid})
+		})
+
+		It("returns ErrInvalidEmail", func() {
+			type s struct {
+				Email string `json:"email" validate:"email"`
+			}
+			errMap := registry.Validate(s{"foo.com"}).(validator.ErrorMap)
+			Expect(errors.Is(errMap["Email"][0], validator.ErrInvalidEmail)).To(BeTrue())
+		})
+	})
 })
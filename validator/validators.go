@@ -1,11 +1,15 @@
 package validator
 
 import (
+	"encoding/base32"
 	"errors"
 	"github.com/lithictech/go-aperitif/v2/kronos"
 	"github.com/rgalanakis/validator"
+	"net/mail"
 	"net/url"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -19,8 +23,19 @@ var (
 	ErrInvalidIntID = newError("not an integer string")
 	// ErrInvalidURL is the error returned when a string cannot be parsed as a request URI.
 	ErrInvalidURL = newError("not a valid url")
+	// ErrInvalidEmail is the error returned when a string is not a bare email address.
+	ErrInvalidEmail = newError("not a valid email")
 	// ErrInvalidUUID4 is the error returned when a string cannot be parsed as a UUID4.
 	ErrInvalidUUID4 = newError("not a uuid4 string")
+	// ErrInvalidULID is the error returned when a string is not a ULID.
+	ErrInvalidULID = newError("not a ulid string")
+	// ErrInvalidBase32 is the error returned when a string is not valid base32.
+	ErrInvalidBase32 = newError("not a base32 string")
+	// ErrUnknownValidator is the error returned by AssertKnownTags
+	// when a struct tag uses a validator that is not registered.
+	ErrUnknownValidator = newError("unknown validator")
+	// ErrEmpty is the error returned when a string, slice, or map is empty, or a pointer is nil.
+	ErrEmpty = newError("must not be empty")
 )
 
 const optional = "opt"
@@ -181,12 +196,96 @@ var uuid4Regexp = regexp.MustCompile("^[0-9a-fA-F-]{32}")
 
 var validateUUID4 = makeStringValidator(ErrInvalidUUID4, uuid4Regexp.MatchString)
 
+// ULIDs are 26 characters of Crockford base32 (case-insensitive, without I, L, O, or U).
+// The first character is at most 7, since a ULID is 128 bits.
+var ulidRegexp = regexp.MustCompile("^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$")
+
+var validateULID = makeStringValidator(ErrInvalidULID, ulidRegexp.MatchString)
+
+var validateBase32 = makeStringValidator(ErrInvalidBase32, func(s string) bool {
+	enc := base32.StdEncoding
+	if !strings.HasSuffix(s, "=") {
+		enc = enc.WithPadding(base32.NoPadding)
+	}
+	_, err := enc.DecodeString(s)
+	return err == nil
+})
+
 var validateURL = makeStringValidator(ErrInvalidURL, func(s string) bool {
 	// using url.Parse is worthless, it treats almost anything as valid
 	_, err := url.ParseRequestURI(s)
 	return err == nil
 })
 
+// validateInSet is like enum, but for numeric fields,
+// so uses reflection to support all the int, uint, and float kinds.
+func validateInSet(v interface{}, param string) error {
+	choices, optional, err := splitOptionalVal(param)
+	if err != nil {
+		return err
+	}
+	nums := make([]float64, len(choices))
+	for i, choice := range choices {
+		if nums[i], err = strconv.ParseFloat(choice, 64); err != nil {
+			return validator.ErrBadParameter
+		}
+	}
+	var n float64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = rv.Float()
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return validator.ErrUnsupported
+	default:
+		return validator.ErrUnsupported
+	}
+	if n == 0 && optional {
+		return nil
+	}
+	for _, choice := range nums {
+		if choice == n {
+			return nil
+		}
+	}
+	return newError("is not one of " + strings.Join(choices, "|"))
+}
+
+var validateEmail = makeStringValidator(ErrInvalidEmail, func(s string) bool {
+	// ParseAddress accepts "Name <a@b.c>" and "<a@b.c>",
+	// so make sure the value is only the addr-spec.
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+})
+
+// validateNotEmpty works across any string, slice, array, or map type,
+// so uses reflection rather than type assertions.
+// Unlike most validators, a nil pointer is invalid, since it has no value.
+func validateNotEmpty(v interface{}, param string) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if rv.Len() == 0 {
+			return ErrEmpty
+		}
+		return nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return ErrEmpty
+		}
+		return validateNotEmpty(rv.Elem().Interface(), param)
+	default:
+		return validator.ErrUnsupported
+	}
+}
+
 func makeValidateCompareNow(getNow nowSource) validator.ValidationFunc {
 	return func(v interface{}, param string) error {
 		validating, ok := v.(time.Time)